@@ -48,30 +48,73 @@ func runServe(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
-	// Find first enabled Anthropic provider
-	var provider providers.Provider
-
-	var providerKey string
+	// Build the provider pool from every enabled provider, in config order.
+	var entries []providers.Entry
 
 	for _, p := range cfg.Providers {
-		if p.Enabled && p.Type == "anthropic" {
-			provider = providers.NewAnthropicProvider(p.Name, p.BaseURL)
+		if !p.Enabled {
+			continue
+		}
+
+		var provider providers.Provider
 
-			if len(p.Keys) > 0 {
-				providerKey = p.Keys[0].Key
+		switch p.Type {
+		case "anthropic":
+			provider = providers.NewAnthropicProviderWithModels(p.Name, p.BaseURL, p.Models)
+		case "zai":
+			provider = providers.NewZAIProviderWithModels(p.Name, p.BaseURL, p.Models)
+		default:
+			continue
+		}
+
+		var key string
+		if len(p.Keys) > 0 {
+			key = p.Keys[0].Key
+		}
+
+		// Providers configured with a token endpoint authenticate via an
+		// OAuth-style Bearer token instead of (or in addition to, as a
+		// fallback on a 401 challenge) the static key above.
+		//
+		// NOTE: today this TokenSource is only ever consulted by
+		// Pool.doAuthenticated from the background health checker (see
+		// Pool.probe) - self-healing an unhealthy provider's status after a
+		// token expires. The actual /v1/messages request path does not yet
+		// retry a live client request on a 401 the same way; that requires
+		// the same doAuthenticated-style retry inside whatever forwards a
+		// request to the chosen provider, which this tree has no handler.go
+		// for. Until that exists, a live request against a provider whose
+		// static key/token has gone stale still fails the request, even
+		// though the health checker will (on its next probe) notice and
+		// recover the provider's reported health using a refreshed token.
+		if p.TokenEndpoint != "" {
+			fetcher := &providers.OAuthTokenFetcher{
+				Endpoint:     p.TokenEndpoint,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RefreshToken: p.RefreshToken,
 			}
 
-			break
+			tokenSource := providers.NewCachingTokenSource(fetcher, 0)
+
+			if setter, ok := provider.(interface{ SetTokenSource(providers.TokenSource) }); ok {
+				setter.SetTokenSource(tokenSource)
+			}
 		}
+
+		entries = append(entries, providers.Entry{Provider: provider, Key: key})
 	}
 
-	if provider == nil {
-		slog.Error("no enabled anthropic provider found in config")
-		return errors.New("no enabled anthropic provider in config")
+	if len(entries) == 0 {
+		slog.Error("no enabled provider found in config")
+		return errors.New("no enabled provider in config")
 	}
 
+	pool := providers.NewPool(entries, cfg.HealthCheck, cfg.Failover)
+	pool.StartHealthChecks(context.Background())
+
 	// Setup routes
-	handler, err := proxy.SetupRoutes(cfg, provider, providerKey)
+	handler, err := proxy.SetupRoutes(cfg, pool)
 	if err != nil {
 		slog.Error("failed to setup routes", "error", err)
 		return err
@@ -80,8 +123,29 @@ func runServe(_ *cobra.Command, _ []string) error {
 	// Create server
 	server := proxy.NewServer(cfg.Server.Listen, handler)
 
+	if cfg.Server.SocketMode != 0 {
+		server.SetSocketMode(os.FileMode(cfg.Server.SocketMode))
+	}
+
+	if cfg.Server.SocketOwner != "" || cfg.Server.SocketGroup != "" {
+		server.SetSocketOwner(cfg.Server.SocketOwner, cfg.Server.SocketGroup)
+	}
+
+	serveTLS := cfg.Server.TLS != nil && cfg.Server.TLS.CertFile != ""
+	if serveTLS {
+		server.SetTLSConfig(&proxy.TLSConfig{
+			CertFile:     cfg.Server.TLS.CertFile,
+			KeyFile:      cfg.Server.TLS.KeyFile,
+			ClientCAFile: cfg.Server.TLS.ClientCAFile,
+			ClientAuth:   proxy.ClientAuthType(cfg.Server.TLS.ClientAuth),
+			MinVersion:   cfg.Server.TLS.MinVersion,
+		})
+	}
+
 	// Graceful shutdown on SIGINT/SIGTERM
+	serverCtx, cancelServer := context.WithCancel(context.Background())
 	done := make(chan struct{})
+
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
@@ -89,6 +153,8 @@ func runServe(_ *cobra.Command, _ []string) error {
 
 		slog.Info("shutting down...")
 
+		cancelServer()
+
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
@@ -100,9 +166,15 @@ func runServe(_ *cobra.Command, _ []string) error {
 	}()
 
 	// Start server
-	slog.Info("starting cc-relay", "listen", cfg.Server.Listen)
+	slog.Info("starting cc-relay", "listen", cfg.Server.Listen, "tls", serveTLS)
+
+	if serveTLS {
+		err = server.ListenAndServeTLS(serverCtx)
+	} else {
+		err = server.ListenAndServe()
+	}
 
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("server error", "error", err)
 		return err
 	}
@@ -0,0 +1,104 @@
+// Package audit implements a structured, redaction-aware log of proxied
+// requests for operators running cc-relay in a regulated environment.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Stage names a point in a proxied request's lifecycle at which an audit
+// Record may be emitted, mirroring Kubernetes audit policy stages.
+type Stage string
+
+const (
+	// StageRequestReceived names the point at which a request has been
+	// authenticated and routed, before it is proxied upstream. No record is
+	// emitted at this stage: proxy.AuditMiddleware writes only
+	// StageResponseComplete records.
+	StageRequestReceived Stage = "RequestReceived"
+	// StageResponseComplete is emitted once the (possibly streamed) response
+	// has finished, carrying final status, latency, and token counts.
+	StageResponseComplete Stage = "ResponseComplete"
+)
+
+// Record is a single audit event for a proxied request.
+type Record struct {
+	Stage             Stage     `json:"stage"`
+	Timestamp         time.Time `json:"timestamp"`
+	RequestID         string    `json:"request_id"`
+	KeyID             string    `json:"key_id,omitempty"`
+	SourceIP          string    `json:"source_ip,omitempty"`
+	Method            string    `json:"method"`
+	Path              string    `json:"path"`
+	Provider          string    `json:"provider,omitempty"`
+	Model             string    `json:"model,omitempty"`
+	InputTokens       int       `json:"input_tokens,omitempty"`
+	OutputTokens      int       `json:"output_tokens,omitempty"`
+	UpstreamStatus    int       `json:"upstream_status,omitempty"`
+	UpstreamLatencyMs float64   `json:"upstream_latency_ms,omitempty"`
+	TotalBytes        int64     `json:"total_bytes,omitempty"`
+	// Body carries a redacted representation of the request/response text,
+	// per the configured RedactionPolicy. Empty when the policy drops it.
+	Body string `json:"body,omitempty"`
+}
+
+// RedactionMode selects how Record.Body is derived from raw message text
+// before it reaches a Sink.
+type RedactionMode string
+
+const (
+	// RedactionDrop discards message bodies entirely. This is the default
+	// for the zero-value RedactionPolicy.
+	RedactionDrop RedactionMode = "drop"
+	// RedactionHash replaces the body with a SHA-256 digest of its
+	// concatenated text, useful for correlating requests without storing
+	// their content.
+	RedactionHash RedactionMode = "hash"
+	// RedactionKeepEdges keeps the first and last KeepChars characters of
+	// the body and elides the middle.
+	RedactionKeepEdges RedactionMode = "keep_edges"
+)
+
+// RedactionPolicy configures how Redact transforms message text.
+type RedactionPolicy struct {
+	Mode RedactionMode
+	// KeepChars is the number of characters kept at each end of the body
+	// under RedactionKeepEdges. Ignored by other modes.
+	KeepChars int
+}
+
+// Redact applies policy to text, returning the value safe to persist in a
+// Record. An unrecognized or zero-value Mode drops the text, so a
+// misconfigured policy never accidentally leaks content.
+func Redact(text string, policy RedactionPolicy) string {
+	switch policy.Mode {
+	case RedactionHash:
+		sum := sha256.Sum256([]byte(text))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case RedactionKeepEdges:
+		return keepEdges(text, policy.KeepChars)
+	case RedactionDrop:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// keepEdges returns the first and last n runes of text, joined by an
+// ellipsis, or text unchanged if it's already short enough. A non-positive n
+// redacts the text entirely, so a misconfigured KeepChars never leaks the
+// full body.
+func keepEdges(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	if len(runes) <= 2*n {
+		return text
+	}
+
+	return string(runes[:n]) + "..." + string(runes[len(runes)-n:])
+}
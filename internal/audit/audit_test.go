@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact_Drop(t *testing.T) {
+	t.Parallel()
+
+	if got := Redact("super secret prompt", RedactionPolicy{Mode: RedactionDrop}); got != "" {
+		t.Errorf("expected drop to produce an empty string, got %q", got)
+	}
+}
+
+func TestRedact_Hash(t *testing.T) {
+	t.Parallel()
+
+	got := Redact("super secret prompt", RedactionPolicy{Mode: RedactionHash})
+
+	if !strings.HasPrefix(got, "sha256:") {
+		t.Fatalf("expected a sha256-prefixed digest, got %q", got)
+	}
+
+	if strings.Contains(got, "secret") {
+		t.Error("expected the hashed output to not contain the original text")
+	}
+}
+
+func TestRedact_KeepEdges(t *testing.T) {
+	t.Parallel()
+
+	got := Redact("abcdefghijklmnopqrstuvwxyz", RedactionPolicy{Mode: RedactionKeepEdges, KeepChars: 3})
+	if got != "abc...xyz" {
+		t.Errorf("expected edges kept with middle elided, got %q", got)
+	}
+}
+
+func TestRedact_KeepEdgesShortTextUnchanged(t *testing.T) {
+	t.Parallel()
+
+	got := Redact("short", RedactionPolicy{Mode: RedactionKeepEdges, KeepChars: 10})
+	if got != "short" {
+		t.Errorf("expected text shorter than 2*KeepChars to pass through unchanged, got %q", got)
+	}
+}
+
+func TestRedact_KeepEdgesZeroCharsDropsEntirely(t *testing.T) {
+	t.Parallel()
+
+	got := Redact("anything at all", RedactionPolicy{Mode: RedactionKeepEdges})
+	if got != "" {
+		t.Errorf("expected an unset KeepChars to redact entirely rather than leak, got %q", got)
+	}
+}
+
+func TestRedact_UnknownModeDropsEntirely(t *testing.T) {
+	t.Parallel()
+
+	got := Redact("anything at all", RedactionPolicy{Mode: "bogus"})
+	if got != "" {
+		t.Errorf("expected an unrecognized mode to redact entirely, got %q", got)
+	}
+}
@@ -0,0 +1,29 @@
+package audit
+
+import "github.com/rs/zerolog/log"
+
+// Logger emits audit Records to a Sink, applying a RedactionPolicy to any
+// message body before it is persisted.
+type Logger struct {
+	sink   Sink
+	policy RedactionPolicy
+}
+
+// NewLogger creates a Logger writing to sink, redacting any Record.Body
+// passed to Log according to policy.
+func NewLogger(sink Sink, policy RedactionPolicy) *Logger {
+	return &Logger{sink: sink, policy: policy}
+}
+
+// Log redacts rec.Body per the configured policy and writes rec to the
+// sink. A sink failure is logged rather than returned, so a broken audit
+// destination never blocks or fails the proxied request it's describing.
+func (l *Logger) Log(rec Record) {
+	if rec.Body != "" {
+		rec.Body = Redact(rec.Body, l.policy)
+	}
+
+	if err := l.sink.Write(rec); err != nil {
+		log.Error().Err(err).Msg("failed to write audit record")
+	}
+}
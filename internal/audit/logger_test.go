@@ -0,0 +1,48 @@
+package audit
+
+import "testing"
+
+// recordingSink captures every Record written to it, for assertions.
+type recordingSink struct {
+	records []Record
+}
+
+func (s *recordingSink) Write(rec Record) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func TestLogger_RedactsBodyBeforeWriting(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	logger := NewLogger(sink, RedactionPolicy{Mode: RedactionHash})
+
+	logger.Log(Record{RequestID: "req-1", KeyID: "key:abcd1234", Body: "my api key is sk-secret-value"})
+
+	if len(sink.records) != 1 {
+		t.Fatalf("expected exactly one record, got %d", len(sink.records))
+	}
+
+	got := sink.records[0]
+	if got.Body == "my api key is sk-secret-value" {
+		t.Fatal("expected the raw body to never reach the sink")
+	}
+
+	if got.KeyID != "key:abcd1234" {
+		t.Errorf("expected KeyID to pass through unredacted, got %q", got.KeyID)
+	}
+}
+
+func TestLogger_DropPolicyNeverWritesBody(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	logger := NewLogger(sink, RedactionPolicy{Mode: RedactionDrop})
+
+	logger.Log(Record{RequestID: "req-1", Body: "sensitive prompt text"})
+
+	if sink.records[0].Body != "" {
+		t.Errorf("expected the drop policy to produce an empty body, got %q", sink.records[0].Body)
+	}
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink persists or forwards audit Records. Implementations must be safe for
+// concurrent use, since Logger.Log is called from every proxied request.
+type Sink interface {
+	Write(rec Record) error
+}
+
+// WriterSink writes one JSON-encoded Record per line to an underlying
+// io.Writer, e.g. os.Stdout.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a Sink that writes newline-delimited JSON to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write encodes rec as a single line of JSON.
+func (s *WriterSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.w).Encode(rec)
+}
+
+// FileSink writes newline-delimited JSON Records to a file, rotating to a
+// timestamped backup once the file exceeds MaxBytes.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	file     *os.File
+	size     int64
+}
+
+// NewFileSink opens (or creates) path for appending and returns a FileSink
+// that rotates it once it exceeds maxBytes. maxBytes <= 0 disables rotation.
+func NewFileSink(path string, maxBytes int64) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %q: %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat audit log %q: %w", path, err)
+	}
+
+	return &FileSink{path: path, maxBytes: maxBytes, file: file, size: info.Size()}, nil
+}
+
+// Write appends rec as a line of JSON, rotating the file first if it would
+// exceed maxBytes.
+func (s *FileSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(data)) > s.maxBytes {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+
+	return err
+}
+
+// rotateLocked renames the current log file aside and opens a fresh one at
+// the original path. Callers must hold s.mu.
+func (s *FileSink) rotateLocked() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	backup := s.path + "." + time.Now().Format("20060102T150405.000000000")
+	if err := os.Rename(s.path, backup); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.size = 0
+
+	return nil
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
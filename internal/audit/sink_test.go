@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterSink_WritesOneJSONLinePerRecord(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	if err := sink.Write(Record{RequestID: "req-1", Stage: StageResponseComplete}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := sink.Write(Record{RequestID: "req-2", Stage: StageResponseComplete}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+}
+
+func TestFileSink_RotatesWhenOverMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := NewFileSink(path, 10) // tiny limit forces rotation on the second write
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Write(Record{RequestID: "req-1"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if err := sink.Write(Record{RequestID: "req-2"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+
+	if len(entries) < 2 {
+		t.Errorf("expected a rotated backup file alongside audit.log, got: %v", entries)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink forwards Records as JSON to the local syslog daemon.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink dials the local syslog daemon, tagging entries with tag.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return &SyslogSink{writer: writer}, nil
+}
+
+// Write sends rec to syslog at INFO severity.
+func (s *SyslogSink) Write(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.writer.Info(string(data))
+}
+
+// Close closes the syslog connection.
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
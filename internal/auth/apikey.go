@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// APIKeyAuthenticator validates the x-api-key header against a single static key.
+type APIKeyAuthenticator struct {
+	expectedHash [sha256.Size]byte
+}
+
+// NewAPIKeyAuthenticator creates an Authenticator for the x-api-key header.
+// The expected key is hashed once at construction time rather than per request.
+func NewAPIKeyAuthenticator(expectedKey string) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{expectedHash: sha256.Sum256([]byte(expectedKey))}
+}
+
+// Validate checks the x-api-key header using a constant-time comparison.
+func (a *APIKeyAuthenticator) Validate(r *http.Request) Result {
+	provided := r.Header.Get("x-api-key")
+	if provided == "" {
+		return Result{Valid: false, Type: TypeAPIKey, Error: "missing x-api-key header"}
+	}
+
+	providedHash := sha256.Sum256([]byte(provided))
+	if subtle.ConstantTimeCompare(providedHash[:], a.expectedHash[:]) != 1 {
+		return Result{Valid: false, Type: TypeAPIKey, Error: "invalid x-api-key"}
+	}
+
+	return Result{Valid: true, Type: TypeAPIKey}
+}
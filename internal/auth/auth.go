@@ -0,0 +1,44 @@
+// Package auth implements pluggable request authentication for cc-relay.
+package auth
+
+import "net/http"
+
+// Type identifies which authentication method validated (or rejected) a request.
+type Type string
+
+const (
+	// TypeAPIKey identifies the static x-api-key authenticator.
+	TypeAPIKey Type = "api_key"
+	// TypeBearer identifies the static bearer-secret authenticator.
+	TypeBearer Type = "bearer"
+	// TypeOIDC identifies the OIDC JWT bearer authenticator.
+	TypeOIDC Type = "oidc"
+)
+
+// Result is the outcome of validating a single request against an Authenticator.
+type Result struct {
+	// Valid is true when the request is authenticated.
+	Valid bool
+	// Type identifies which authenticator produced this result.
+	Type Type
+	// Error is a human-readable reason for failure; empty when Valid is true.
+	Error string
+	// Subject is the authenticated principal, when the method produces one
+	// (the OIDC "sub" claim, a client certificate CN, etc). Empty otherwise.
+	Subject string
+	// Capabilities scopes what the authenticated request is permitted to do,
+	// when the authenticator enforces per-principal scoping
+	// (ScopedAPIKeyAuthenticator and ScopedCertAuthenticator). Nil means
+	// unrestricted.
+	Capabilities *KeyCapabilities
+}
+
+// Authenticator validates a single request using one authentication method.
+// Chain (or skip) multiple Authenticators via NewChainAuthenticator.
+type Authenticator interface {
+	// Validate checks the request's credentials and returns the outcome.
+	// Authenticators that find no credentials of their kind (e.g. no
+	// Authorization header for a bearer authenticator) should return an
+	// invalid Result rather than panicking, so the chain can try the next one.
+	Validate(r *http.Request) Result
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuthenticator_Validate(t *testing.T) {
+	t.Parallel()
+
+	authr := NewAPIKeyAuthenticator("secret-key")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "secret-key")
+
+	if result := authr.Validate(req); !result.Valid {
+		t.Errorf("expected valid result, got error: %s", result.Error)
+	}
+
+	req.Header.Set("x-api-key", "wrong-key")
+
+	if result := authr.Validate(req); result.Valid {
+		t.Error("expected invalid result for wrong key")
+	}
+}
+
+func TestBearerAuthenticator_Validate(t *testing.T) {
+	t.Parallel()
+
+	authr := NewBearerAuthenticator("secret-token")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	if result := authr.Validate(req); !result.Valid {
+		t.Errorf("expected valid result, got error: %s", result.Error)
+	}
+
+	req.Header.Set("Authorization", "Bearer wrong-token")
+
+	if result := authr.Validate(req); result.Valid {
+		t.Error("expected invalid result for wrong token")
+	}
+}
+
+func TestChainAuthenticator_TriesEachInOrder(t *testing.T) {
+	t.Parallel()
+
+	chain := NewChainAuthenticator(
+		NewBearerAuthenticator("bearer-secret"),
+		NewAPIKeyAuthenticator("api-key"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "api-key")
+
+	result := chain.Validate(req)
+	if !result.Valid || result.Type != TypeAPIKey {
+		t.Errorf("expected valid api_key result, got %+v", result)
+	}
+}
+
+func TestChainAuthenticator_ReturnsLastFailure(t *testing.T) {
+	t.Parallel()
+
+	chain := NewChainAuthenticator(
+		NewBearerAuthenticator("bearer-secret"),
+		NewAPIKeyAuthenticator("api-key"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+
+	result := chain.Validate(req)
+	if result.Valid {
+		t.Error("expected invalid result with no credentials presented")
+	}
+
+	if result.Type != TypeAPIKey {
+		t.Errorf("expected the last authenticator's (api_key) failure, got %+v", result)
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// BearerAuthenticator validates an `Authorization: Bearer <secret>` header
+// against a single static secret.
+type BearerAuthenticator struct {
+	expectedHash [sha256.Size]byte
+}
+
+// NewBearerAuthenticator creates an Authenticator for a static bearer secret.
+func NewBearerAuthenticator(secret string) *BearerAuthenticator {
+	return &BearerAuthenticator{expectedHash: sha256.Sum256([]byte(secret))}
+}
+
+// Validate checks the Authorization header using a constant-time comparison.
+func (a *BearerAuthenticator) Validate(r *http.Request) Result {
+	header := r.Header.Get("Authorization")
+
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return Result{Valid: false, Type: TypeBearer, Error: "missing bearer token"}
+	}
+
+	tokenHash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(tokenHash[:], a.expectedHash[:]) != 1 {
+		return Result{Valid: false, Type: TypeBearer, Error: "invalid bearer token"}
+	}
+
+	return Result{Valid: true, Type: TypeBearer}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/x509/pkix"
+	"net/http"
+	"net/url"
+)
+
+// TypeCert identifies the mTLS client-certificate authenticator.
+const TypeCert Type = "cert"
+
+// CertAuthenticator authenticates requests that arrived over an mTLS
+// listener with `client_auth=require_and_verify`, using the already-verified
+// leaf client certificate attached to the request's TLS connection state.
+// It never rejects a request on its own initiative: if no verified peer
+// certificate is present, it returns an invalid Result so the chain can
+// fall through to another authenticator (or fail overall).
+type CertAuthenticator struct{}
+
+// NewCertAuthenticator creates a CertAuthenticator.
+func NewCertAuthenticator() *CertAuthenticator {
+	return &CertAuthenticator{}
+}
+
+// Validate extracts the CN (or SPIFFE URI SAN, when present) from the
+// verified client certificate chain on r.TLS.
+func (a *CertAuthenticator) Validate(r *http.Request) Result {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Result{Valid: false, Type: TypeCert, Error: "no verified client certificate"}
+	}
+
+	leaf := r.TLS.VerifiedChains[0][0]
+
+	subject := spiffeID(leaf.URIs)
+	if subject == "" {
+		subject = commonName(leaf.Subject)
+	}
+
+	if subject == "" {
+		return Result{Valid: false, Type: TypeCert, Error: "client certificate has no CN or SPIFFE ID"}
+	}
+
+	return Result{Valid: true, Type: TypeCert, Subject: subject}
+}
+
+func commonName(subject pkix.Name) string {
+	return subject.CommonName
+}
+
+func spiffeID(uris []*url.URL) string {
+	for _, u := range uris {
+		if u != nil && u.Scheme == "spiffe" {
+			return u.String()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,32 @@
+package auth
+
+import "net/http"
+
+// ChainAuthenticator tries a sequence of Authenticators in order and succeeds
+// as soon as one of them validates the request.
+type ChainAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuthenticator creates a ChainAuthenticator over the given
+// authenticators, tried in the order provided.
+func NewChainAuthenticator(authenticators ...Authenticator) *ChainAuthenticator {
+	return &ChainAuthenticator{authenticators: authenticators}
+}
+
+// Validate tries each authenticator in order, returning the first valid
+// Result. If none succeed, it returns the last failure encountered.
+func (c *ChainAuthenticator) Validate(r *http.Request) Result {
+	var last Result
+
+	for _, a := range c.authenticators {
+		result := a.Validate(r)
+		if result.Valid {
+			return result
+		}
+
+		last = result
+	}
+
+	return last
+}
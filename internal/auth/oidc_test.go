@@ -0,0 +1,38 @@
+package auth
+
+import "testing"
+
+func TestOIDCAuthenticator_CheckScopes(t *testing.T) {
+	t.Parallel()
+
+	a := &OIDCAuthenticator{cfg: OIDCConfig{RequiredScopes: []string{"cc-relay:invoke"}}}
+
+	if err := a.checkScopes(claims{Scope: "cc-relay:invoke other:scope"}); err != nil {
+		t.Errorf("expected required scope to be satisfied, got: %v", err)
+	}
+
+	if err := a.checkScopes(claims{Scope: "other:scope"}); err == nil {
+		t.Error("expected error for missing required scope")
+	}
+}
+
+func TestOIDCAuthenticator_CheckScopesNoneRequired(t *testing.T) {
+	t.Parallel()
+
+	a := &OIDCAuthenticator{}
+
+	if err := a.checkScopes(claims{}); err != nil {
+		t.Errorf("expected no error when no scopes are required, got: %v", err)
+	}
+}
+
+func TestWWWAuthenticateHeader(t *testing.T) {
+	t.Parallel()
+
+	got := WWWAuthenticateHeader("invalid_token", "token expired")
+	want := `Bearer realm="cc-relay", error="invalid_token", error_description="token expired"`
+
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
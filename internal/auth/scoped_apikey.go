@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// KeyCapabilities scopes what a single API key is permitted to do: which
+// HTTP paths it may call, which model prefixes it may request, how many
+// requests per second it may issue, and when it expires. A zero-value field
+// means "unrestricted" for that dimension, except ExpiresAt (zero means
+// "never expires").
+type KeyCapabilities struct {
+	// Paths lists the exact HTTP paths this key may call, e.g. "/v1/messages".
+	// Empty means any path.
+	Paths []string
+	// Models lists model-name prefixes this key may request. Empty means any
+	// model.
+	Models []string
+	// RPS caps requests per second for this key. Zero or negative means
+	// unlimited.
+	RPS float64
+	// ExpiresAt is the key's expiry time. The zero value means it never
+	// expires.
+	ExpiresAt time.Time
+	// DefaultProvider names the provider this key should route to absent a
+	// more specific match (a header override or a model-based rule). Empty
+	// means no key-specific default.
+	DefaultProvider string
+}
+
+// scopedKey pairs a configured key's SHA-256 hash with its capabilities, so
+// Validate never compares against the raw secret directly - the same
+// timing-attack hardening APIKeyAuthenticator applies to its single static
+// key.
+type scopedKey struct {
+	hash [sha256.Size]byte
+	caps KeyCapabilities
+}
+
+// ScopedAPIKeyAuthenticator validates the x-api-key header against a set of
+// keys, each carrying its own KeyCapabilities, so operators can hand out
+// differently scoped keys (e.g. model-restricted, rate-limited) to
+// different callers from a single relay. Unlike APIKeyAuthenticator, which
+// checks one global key, this checks the provided key against every
+// configured key.
+type ScopedAPIKeyAuthenticator struct {
+	keys []scopedKey
+}
+
+// NewScopedAPIKeyAuthenticator creates an Authenticator over keys, keyed by
+// the raw x-api-key value each caller presents. Each key is hashed once here
+// rather than per request.
+func NewScopedAPIKeyAuthenticator(keys map[string]KeyCapabilities) *ScopedAPIKeyAuthenticator {
+	scoped := make([]scopedKey, 0, len(keys))
+	for k, caps := range keys {
+		scoped = append(scoped, scopedKey{hash: sha256.Sum256([]byte(k)), caps: caps})
+	}
+
+	return &ScopedAPIKeyAuthenticator{keys: scoped}
+}
+
+// Validate checks the x-api-key header against the configured key set using
+// a constant-time comparison per key - a direct map lookup on the raw key
+// would reintroduce the timing side channel APIKeyAuthenticator is
+// deliberately hardened against - and rejects expired keys. The Subject of a
+// successful Result is a short, non-reversible fingerprint of the key, safe
+// to carry into logs.
+func (a *ScopedAPIKeyAuthenticator) Validate(r *http.Request) Result {
+	provided := r.Header.Get("x-api-key")
+	if provided == "" {
+		return Result{Valid: false, Type: TypeAPIKey, Error: "missing x-api-key header"}
+	}
+
+	providedHash := sha256.Sum256([]byte(provided))
+
+	var (
+		caps  KeyCapabilities
+		found bool
+	)
+
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(providedHash[:], k.hash[:]) == 1 {
+			caps = k.caps
+			found = true
+		}
+	}
+
+	if !found {
+		return Result{Valid: false, Type: TypeAPIKey, Error: "invalid x-api-key"}
+	}
+
+	if !caps.ExpiresAt.IsZero() && time.Now().After(caps.ExpiresAt) {
+		return Result{Valid: false, Type: TypeAPIKey, Error: "x-api-key has expired"}
+	}
+
+	return Result{Valid: true, Type: TypeAPIKey, Subject: "key:" + keyFingerprint(provided), Capabilities: &caps}
+}
+
+// keyFingerprint returns a short, non-reversible identifier for key,
+// suitable for logging without leaking the credential itself.
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
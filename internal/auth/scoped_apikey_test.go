@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScopedAPIKeyAuthenticator_Validate(t *testing.T) {
+	t.Parallel()
+
+	authr := NewScopedAPIKeyAuthenticator(map[string]KeyCapabilities{
+		"haiku-only": {Models: []string{"claude-3-haiku"}, RPS: 5},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "haiku-only")
+
+	result := authr.Validate(req)
+	if !result.Valid {
+		t.Fatalf("expected valid result, got error: %s", result.Error)
+	}
+
+	if result.Capabilities == nil {
+		t.Fatal("expected capabilities to be attached to the result")
+	}
+
+	if result.Capabilities.RPS != 5 {
+		t.Errorf("expected RPS 5, got %v", result.Capabilities.RPS)
+	}
+
+	req.Header.Set("x-api-key", "unknown-key")
+
+	if result := authr.Validate(req); result.Valid {
+		t.Error("expected invalid result for an unknown key")
+	}
+}
+
+func TestScopedAPIKeyAuthenticator_RejectsExpiredKey(t *testing.T) {
+	t.Parallel()
+
+	authr := NewScopedAPIKeyAuthenticator(map[string]KeyCapabilities{
+		"expired-key": {ExpiresAt: time.Now().Add(-time.Hour)},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "expired-key")
+
+	result := authr.Validate(req)
+	if result.Valid {
+		t.Error("expected invalid result for an expired key")
+	}
+}
+
+func TestScopedAPIKeyAuthenticator_SubjectDoesNotLeakKey(t *testing.T) {
+	t.Parallel()
+
+	authr := NewScopedAPIKeyAuthenticator(map[string]KeyCapabilities{
+		"super-secret-key": {},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "super-secret-key")
+
+	result := authr.Validate(req)
+	if !result.Valid {
+		t.Fatalf("expected valid result, got error: %s", result.Error)
+	}
+
+	if result.Subject == "super-secret-key" {
+		t.Error("expected Subject to be a fingerprint, not the raw key")
+	}
+}
+
+func TestScopedAPIKeyAuthenticator_MatchesCorrectKeyAmongMany(t *testing.T) {
+	t.Parallel()
+
+	authr := NewScopedAPIKeyAuthenticator(map[string]KeyCapabilities{
+		"key-a": {RPS: 1},
+		"key-b": {RPS: 2},
+		"key-c": {RPS: 3},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.Header.Set("x-api-key", "key-b")
+
+	result := authr.Validate(req)
+	if !result.Valid {
+		t.Fatalf("expected valid result, got error: %s", result.Error)
+	}
+
+	if result.Capabilities.RPS != 2 {
+		t.Errorf("expected the capabilities for key-b, got RPS %v", result.Capabilities.RPS)
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+)
+
+// ScopedCertAuthenticator authenticates requests the same way
+// CertAuthenticator does, using the verified mTLS client certificate
+// attached to the connection, but additionally scopes the request with
+// KeyCapabilities looked up by the certificate's identity (its CN, or
+// SPIFFE URI SAN when present) - the same capability model already used for
+// API keys via ScopedAPIKeyAuthenticator. Identities with no entry in certs
+// are rejected rather than treated as unrestricted.
+type ScopedCertAuthenticator struct {
+	certs map[string]KeyCapabilities
+	inner *CertAuthenticator
+}
+
+// NewScopedCertAuthenticator creates a ScopedCertAuthenticator authorizing
+// only the certificate identities present in certs.
+func NewScopedCertAuthenticator(certs map[string]KeyCapabilities) *ScopedCertAuthenticator {
+	return &ScopedCertAuthenticator{certs: certs, inner: NewCertAuthenticator()}
+}
+
+// Validate verifies the client certificate exactly like CertAuthenticator,
+// then requires its identity to have a matching, unexpired entry in certs.
+func (a *ScopedCertAuthenticator) Validate(r *http.Request) Result {
+	result := a.inner.Validate(r)
+	if !result.Valid {
+		return result
+	}
+
+	caps, ok := a.certs[result.Subject]
+	if !ok {
+		return Result{Valid: false, Type: TypeCert, Error: "client certificate identity is not authorized"}
+	}
+
+	if !caps.ExpiresAt.IsZero() && time.Now().After(caps.ExpiresAt) {
+		return Result{Valid: false, Type: TypeCert, Error: "client certificate identity capabilities have expired"}
+	}
+
+	result.Capabilities = &caps
+
+	return result
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// verifiedRequestWithCN builds a request whose r.TLS.VerifiedChains carries
+// a single self-signed leaf certificate with the given CN, mimicking what
+// net/http populates after a successful mTLS handshake.
+func verifiedRequestWithCN(t *testing.T, cn string) *http.Request {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+
+	return req
+}
+
+func TestScopedCertAuthenticator_ValidatesKnownCN(t *testing.T) {
+	t.Parallel()
+
+	auth := NewScopedCertAuthenticator(map[string]KeyCapabilities{
+		"agent-1": {Models: []string{"claude-"}},
+	})
+
+	result := auth.Validate(verifiedRequestWithCN(t, "agent-1"))
+	if !result.Valid {
+		t.Fatalf("expected a known CN to authenticate, got error: %s", result.Error)
+	}
+
+	if result.Subject != "agent-1" {
+		t.Errorf("expected Subject %q, got %q", "agent-1", result.Subject)
+	}
+
+	if result.Capabilities == nil || len(result.Capabilities.Models) != 1 {
+		t.Fatal("expected the configured capabilities to be attached to the result")
+	}
+}
+
+func TestScopedCertAuthenticator_RejectsUnknownCN(t *testing.T) {
+	t.Parallel()
+
+	auth := NewScopedCertAuthenticator(map[string]KeyCapabilities{
+		"agent-1": {},
+	})
+
+	result := auth.Validate(verifiedRequestWithCN(t, "agent-2"))
+	if result.Valid {
+		t.Fatal("expected an unconfigured CN to be rejected")
+	}
+}
+
+func TestScopedCertAuthenticator_RejectsExpiredCapabilities(t *testing.T) {
+	t.Parallel()
+
+	auth := NewScopedCertAuthenticator(map[string]KeyCapabilities{
+		"agent-1": {ExpiresAt: time.Now().Add(-time.Minute)},
+	})
+
+	result := auth.Validate(verifiedRequestWithCN(t, "agent-1"))
+	if result.Valid {
+		t.Fatal("expected expired capabilities to reject the request")
+	}
+}
+
+func TestScopedCertAuthenticator_RejectsMissingCertificate(t *testing.T) {
+	t.Parallel()
+
+	auth := NewScopedCertAuthenticator(map[string]KeyCapabilities{"agent-1": {}})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+
+	result := auth.Validate(req)
+	if result.Valid {
+		t.Fatal("expected a request with no client certificate to be rejected")
+	}
+}
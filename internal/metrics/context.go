@@ -0,0 +1,65 @@
+package metrics
+
+import "context"
+
+// ctxKey is an unexported type to avoid collisions with context keys from other packages.
+type ctxKey int
+
+const (
+	providerKey ctxKey = iota
+	modelKey
+	authTypeKey
+	streamKey
+)
+
+// WithProvider annotates ctx with the name of the provider selected to serve the request.
+func WithProvider(ctx context.Context, provider string) context.Context {
+	return context.WithValue(ctx, providerKey, provider)
+}
+
+// WithModel annotates ctx with the model extracted from the request body.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey, model)
+}
+
+// WithAuthType annotates ctx with the authentication method that validated the request.
+func WithAuthType(ctx context.Context, authType string) context.Context {
+	return context.WithValue(ctx, authTypeKey, authType)
+}
+
+// WithStream annotates ctx with whether the request was a streaming request.
+func WithStream(ctx context.Context, stream bool) context.Context {
+	return context.WithValue(ctx, streamKey, stream)
+}
+
+func providerLabel(ctx context.Context) string {
+	v, _ := ctx.Value(providerKey).(string)
+	if v == "" {
+		return "unknown"
+	}
+
+	return v
+}
+
+func modelLabel(ctx context.Context) string {
+	v, _ := ctx.Value(modelKey).(string)
+	if v == "" {
+		return "unknown"
+	}
+
+	return v
+}
+
+func authTypeLabel(ctx context.Context) string {
+	v, _ := ctx.Value(authTypeKey).(string)
+	if v == "" {
+		return "none"
+	}
+
+	return v
+}
+
+func streamLabel(ctx context.Context) bool {
+	v, _ := ctx.Value(streamKey).(bool)
+	return v
+}
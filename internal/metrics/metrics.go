@@ -0,0 +1,253 @@
+// Package metrics exposes Prometheus instrumentation for the proxy middleware chain.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Version, Commit and GoVersion are populated by the build (e.g. via -ldflags)
+// and reported as labels of ccrelay_build_info; local builds report Version
+// "dev" and "unknown" for the other two.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	GoVersion = "unknown"
+)
+
+// Registry wraps a dedicated Prometheus registry and the collectors cc-relay
+// reports on. A dedicated registry (rather than the global default) keeps
+// metrics isolated across multiple Registry instances in tests.
+type Registry struct {
+	reg *prometheus.Registry
+
+	requestsTotal       *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+	requestBodyBytes    *prometheus.HistogramVec
+	responseBodyBytes   *prometheus.HistogramVec
+	upstreamErrorsTotal *prometheus.CounterVec
+	upstreamLatency     *prometheus.HistogramVec
+	providerUp          *prometheus.GaugeVec
+	inFlightRequests    prometheus.Gauge
+	streamTokensTotal   *prometheus.CounterVec
+}
+
+// New creates a Registry with all cc-relay collectors registered, plus the Go
+// runtime and process collectors.
+func New() *Registry {
+	reg := prometheus.NewRegistry()
+
+	labels := []string{"provider", "model", "status", "auth_type", "stream"}
+
+	r := &Registry{
+		reg: reg,
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccrelay_http_requests_total",
+			Help: "Total number of HTTP requests handled by cc-relay.",
+		}, labels),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccrelay_http_request_duration_seconds",
+			Help:    "Duration of HTTP requests handled by cc-relay.",
+			Buckets: []float64{0.1, 0.3, 1.2, 5},
+		}, labels),
+		requestBodyBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccrelay_http_request_body_bytes",
+			Help:    "Size of proxied request bodies in bytes.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"provider", "model"}),
+		responseBodyBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccrelay_http_response_body_bytes",
+			Help:    "Size of proxied response bodies in bytes.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"provider", "model"}),
+		upstreamErrorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccrelay_upstream_errors_total",
+			Help: "Total number of upstream provider errors (5xx or connection failures).",
+		}, []string{"provider"}),
+		upstreamLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ccrelay_upstream_latency_seconds",
+			Help:    "Latency of proxied requests to a backend provider.",
+			Buckets: []float64{0.1, 0.3, 1.2, 5},
+		}, []string{"provider"}),
+		providerUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ccrelay_provider_up",
+			Help: "Whether a provider is currently considered healthy (1) or not (0).",
+		}, []string{"provider"}),
+		inFlightRequests: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ccrelay_in_flight_requests",
+			Help: "Number of HTTP requests currently being handled by cc-relay.",
+		}),
+		streamTokensTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ccrelay_stream_tokens_total",
+			Help: "Total tokens reported by streamed Anthropic SSE usage events, by kind.",
+		}, []string{"provider", "model", "kind"}),
+	}
+
+	reg.MustRegister(
+		r.requestsTotal,
+		r.requestDuration,
+		r.requestBodyBytes,
+		r.responseBodyBytes,
+		r.upstreamErrorsTotal,
+		r.upstreamLatency,
+		r.providerUp,
+		r.inFlightRequests,
+		r.streamTokensTotal,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "ccrelay_build_info",
+		Help:        "Build information for the running cc-relay binary.",
+		ConstLabels: prometheus.Labels{"version": Version, "commit": Commit, "go_version": GoVersion},
+	})
+	buildInfo.Set(1)
+	reg.MustRegister(buildInfo)
+
+	return r
+}
+
+// Handler returns the HTTP handler that serves the registry in the
+// Prometheus exposition format, suitable for mounting at /metrics.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{Registry: r.reg})
+}
+
+// SetProviderUp records the current health of a named provider.
+func (r *Registry) SetProviderUp(provider string, up bool) {
+	v := 0.0
+	if up {
+		v = 1.0
+	}
+
+	r.providerUp.WithLabelValues(provider).Set(v)
+}
+
+// ObserveUpstreamError increments the upstream error counter for provider.
+func (r *Registry) ObserveUpstreamError(provider string) {
+	r.upstreamErrorsTotal.WithLabelValues(provider).Inc()
+}
+
+// ObserveBodies records request/response body sizes for a proxied call.
+// reqBytes is typically taken from the request's Content-Length and is 0
+// for chunked or otherwise unknown-length request bodies.
+func (r *Registry) ObserveBodies(provider, model string, reqBytes, respBytes int) {
+	r.requestBodyBytes.WithLabelValues(provider, model).Observe(float64(reqBytes))
+	r.responseBodyBytes.WithLabelValues(provider, model).Observe(float64(respBytes))
+}
+
+// ObserveUpstreamLatency records how long a proxied call to provider took.
+func (r *Registry) ObserveUpstreamLatency(provider string, d time.Duration) {
+	r.upstreamLatency.WithLabelValues(provider).Observe(d.Seconds())
+}
+
+// TokenUsage holds the token counts reported by an Anthropic SSE usage event
+// (message_start or message_delta). Zero-valued fields are not recorded.
+type TokenUsage struct {
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+}
+
+// ObserveTokenUsage records the token counts extracted from a streamed
+// response's usage events, labeled by provider, model, and token kind.
+func (r *Registry) ObserveTokenUsage(provider, model string, usage TokenUsage) {
+	if usage.InputTokens > 0 {
+		r.streamTokensTotal.WithLabelValues(provider, model, "input").Add(float64(usage.InputTokens))
+	}
+
+	if usage.OutputTokens > 0 {
+		r.streamTokensTotal.WithLabelValues(provider, model, "output").Add(float64(usage.OutputTokens))
+	}
+
+	if usage.CacheReadTokens > 0 {
+		r.streamTokensTotal.WithLabelValues(provider, model, "cache_read").Add(float64(usage.CacheReadTokens))
+	}
+
+	if usage.CacheWriteTokens > 0 {
+		r.streamTokensTotal.WithLabelValues(provider, model, "cache_write").Add(float64(usage.CacheWriteTokens))
+	}
+}
+
+// MetricsMiddleware records request counts, duration, body sizes and upstream
+// errors labeled by provider, model, status, auth_type and stream. It is
+// designed to sit between LoggingMiddleware and the proxy handler in
+// proxy.SetupRoutes, so that the provider/model chosen by routing (when
+// present in the request context) are available for labeling.
+//
+// ccrelay_upstream_latency_seconds is deliberately NOT recorded here:
+// duration below is the full handler time, including however long a
+// streaming client takes to consume an SSE response, not the latency of the
+// call to the backend. That metric is instead fed by Router.RecordLatency,
+// the one place with the actual per-call backend latency.
+func MetricsMiddleware(reg *Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			reg.inFlightRequests.Inc()
+			defer reg.inFlightRequests.Dec()
+
+			start := time.Now()
+
+			wrapped := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, req)
+
+			duration := time.Since(start)
+			provider := providerLabel(req.Context())
+			model := modelLabel(req.Context())
+
+			labels := prometheus.Labels{
+				"provider":  provider,
+				"model":     model,
+				"status":    strconv.Itoa(wrapped.statusCode),
+				"auth_type": authTypeLabel(req.Context()),
+				"stream":    strconv.FormatBool(streamLabel(req.Context())),
+			}
+
+			reg.requestsTotal.With(labels).Inc()
+			reg.requestDuration.With(labels).Observe(duration.Seconds())
+
+			if provider == "unknown" {
+				return
+			}
+
+			reqBytes := 0
+			if req.ContentLength > 0 {
+				reqBytes = int(req.ContentLength)
+			}
+
+			reg.ObserveBodies(provider, model, reqBytes, int(wrapped.bytesWritten))
+
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				reg.ObserveUpstreamError(provider)
+			}
+		})
+	}
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the response status
+// code and the total bytes of the response body written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.statusCode = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(p)
+	s.bytesWritten += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsMiddleware_RecordsRequest(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithProvider(r.Context(), "anthropic-primary")
+		ctx = WithModel(ctx, "claude-3-5-sonnet")
+		*r = *r.WithContext(ctx)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := MetricsMiddleware(reg)(next)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	scrapeReq := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	scrapeRec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(scrapeRec, scrapeReq)
+
+	body := scrapeRec.Body.String()
+	if !strings.Contains(body, "ccrelay_http_requests_total") {
+		t.Error("expected ccrelay_http_requests_total in scrape output")
+	}
+
+	if !strings.Contains(body, "ccrelay_build_info") {
+		t.Error("expected ccrelay_build_info in scrape output")
+	}
+}
+
+func TestRegistry_SetProviderUp(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+	reg.SetProviderUp("primary", true)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `ccrelay_provider_up{provider="primary"} 1`) {
+		t.Errorf("expected provider_up gauge set to 1, got body: %s", rec.Body.String())
+	}
+}
+
+func TestMetricsMiddleware_DoesNotRecordUpstreamLatencyAndRestoresInFlightGauge(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithProvider(r.Context(), "anthropic-primary")
+		*r = *r.WithContext(ctx)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := MetricsMiddleware(reg)(next)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	scrapeReq := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	scrapeRec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(scrapeRec, scrapeReq)
+
+	body := scrapeRec.Body.String()
+	if strings.Contains(body, "ccrelay_upstream_latency_seconds") {
+		t.Errorf("expected MetricsMiddleware to leave ccrelay_upstream_latency_seconds to Router.RecordLatency, got: %s", body)
+	}
+
+	if !strings.Contains(body, "ccrelay_in_flight_requests 0") {
+		t.Errorf("expected in-flight gauge to return to 0 after the request completes, got: %s", body)
+	}
+}
+
+func TestMetricsMiddleware_RecordsBodiesAndUpstreamErrors(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithProvider(r.Context(), "anthropic-primary")
+		ctx = WithModel(ctx, "claude-3-5-sonnet")
+		*r = *r.WithContext(ctx)
+		w.WriteHeader(http.StatusBadGateway)
+		//nolint:errcheck // test response body
+		w.Write([]byte(`{"error":"bad gateway"}`))
+	})
+
+	handler := MetricsMiddleware(reg)(next)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-3-5-sonnet"}`))
+	req.ContentLength = int64(len(`{"model":"claude-3-5-sonnet"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	scrapeReq := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	scrapeRec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(scrapeRec, scrapeReq)
+
+	body := scrapeRec.Body.String()
+	if !strings.Contains(body, `ccrelay_http_request_body_bytes_count{model="claude-3-5-sonnet",provider="anthropic-primary"} 1`) {
+		t.Errorf("expected a request body size observation, got: %s", body)
+	}
+
+	if !strings.Contains(body, `ccrelay_http_response_body_bytes_count{model="claude-3-5-sonnet",provider="anthropic-primary"} 1`) {
+		t.Errorf("expected a response body size observation, got: %s", body)
+	}
+
+	if !strings.Contains(body, `ccrelay_upstream_errors_total{provider="anthropic-primary"} 1`) {
+		t.Errorf("expected the 502 response to count as an upstream error, got: %s", body)
+	}
+}
+
+func TestMetricsMiddleware_UnknownProviderSkipsBodiesAndErrors(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	handler := MetricsMiddleware(reg)(next)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	scrapeReq := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	scrapeRec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(scrapeRec, scrapeReq)
+
+	body := scrapeRec.Body.String()
+	if strings.Contains(body, "ccrelay_upstream_errors_total") {
+		t.Errorf("expected no upstream error observation without a resolved provider, got: %s", body)
+	}
+}
+
+func TestRegistry_ObserveTokenUsage(t *testing.T) {
+	t.Parallel()
+
+	reg := New()
+	reg.ObserveTokenUsage("anthropic-primary", "claude-3-5-sonnet", TokenUsage{
+		InputTokens:      25,
+		OutputTokens:     10,
+		CacheReadTokens:  5,
+		CacheWriteTokens: 2,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`ccrelay_stream_tokens_total{kind="input",model="claude-3-5-sonnet",provider="anthropic-primary"} 25`,
+		`ccrelay_stream_tokens_total{kind="output",model="claude-3-5-sonnet",provider="anthropic-primary"} 10`,
+		`ccrelay_stream_tokens_total{kind="cache_read",model="claude-3-5-sonnet",provider="anthropic-primary"} 5`,
+		`ccrelay_stream_tokens_total{kind="cache_write",model="claude-3-5-sonnet",provider="anthropic-primary"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected scrape output to contain %q, got: %s", want, body)
+		}
+	}
+}
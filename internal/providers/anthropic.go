@@ -14,9 +14,10 @@ const (
 
 // AnthropicProvider implements the Provider interface for Anthropic's API.
 type AnthropicProvider struct {
-	name    string
-	baseURL string
-	models  []string
+	name        string
+	baseURL     string
+	models      []string
+	tokenSource TokenSource
 }
 
 // NewAnthropicProvider creates a new Anthropic provider instance.
@@ -88,6 +89,21 @@ func (p *AnthropicProvider) SupportsStreaming() bool {
 	return true
 }
 
+// TokenSource returns the TokenSource set via SetTokenSource, or nil.
+// Anthropic normally authenticates with the static x-api-key set in
+// Authenticate; a TokenSource is only needed for deployments (e.g. a
+// Vertex-fronted backend) that challenge with a Bearer token instead.
+func (p *AnthropicProvider) TokenSource() TokenSource {
+	return p.tokenSource
+}
+
+// SetTokenSource attaches a TokenSource the proxy should consult when a
+// request to this provider is challenged with a Bearer WWW-Authenticate
+// header, instead of the static x-api-key.
+func (p *AnthropicProvider) SetTokenSource(ts TokenSource) {
+	p.tokenSource = ts
+}
+
 // Owner returns the owner identifier for Anthropic.
 func (p *AnthropicProvider) Owner() string {
 	return "anthropic"
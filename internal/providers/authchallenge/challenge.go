@@ -0,0 +1,188 @@
+// Package authchallenge parses RFC 7235 WWW-Authenticate challenge headers,
+// modeled on the challenge-parsing state machine used by the docker
+// distribution client for its Bearer token flow.
+package authchallenge
+
+import "strings"
+
+// Challenge is a single parsed authentication challenge, e.g.
+//
+//	Bearer realm="https://auth.example.com/token",service="registry",scope="repo:pull"
+type Challenge struct {
+	// Scheme is the auth scheme, lowercased ("bearer", "basic").
+	Scheme string
+	// Realm is the token endpoint URL, when present.
+	Realm string
+	// Service identifies the resource server, when present.
+	Service string
+	// Scope is the requested access scope, when present.
+	Scope string
+	// Params holds every parameter, including realm/service/scope, keyed by
+	// lowercased parameter name, for schemes with additional parameters.
+	Params map[string]string
+}
+
+// ParseAll parses every challenge in a WWW-Authenticate header value. A
+// response may carry multiple challenges (one per scheme) separated by
+// commas at the top level, while each challenge's own parameters are also
+// comma-separated - distinguishing the two requires tracking quoted strings.
+func ParseAll(header string) []Challenge {
+	var challenges []Challenge
+
+	for _, part := range splitChallenges(header) {
+		c, ok := parseOne(part)
+		if ok {
+			challenges = append(challenges, c)
+		}
+	}
+
+	return challenges
+}
+
+// splitChallenges splits a WWW-Authenticate header into one segment per
+// challenge. A new challenge begins at a top-level (unquoted) comma that is
+// followed by a scheme token (a bare word with no '=') rather than a
+// "key=value" parameter continuation.
+func splitChallenges(header string) []string {
+	var (
+		segments []string
+		buf      strings.Builder
+		inQuotes bool
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			segments = append(segments, s)
+		}
+
+		buf.Reset()
+	}
+
+	runes := []rune(header)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+			buf.WriteRune(ch)
+		case ch == ',' && !inQuotes:
+			rest := strings.TrimSpace(string(runes[i+1:]))
+			if startsNewChallenge(rest) {
+				flush()
+			} else {
+				buf.WriteRune(ch)
+			}
+		default:
+			buf.WriteRune(ch)
+		}
+	}
+
+	flush()
+
+	return segments
+}
+
+// startsNewChallenge reports whether rest begins a new "scheme param=..."
+// challenge rather than continuing a parameter list, i.e. it starts with a
+// token followed by whitespace and then a "key=" parameter (no leading '=').
+func startsNewChallenge(rest string) bool {
+	fields := strings.SplitN(rest, " ", 2)
+	if len(fields) != 2 {
+		return false
+	}
+
+	scheme := fields[0]
+	if scheme == "" || strings.ContainsAny(scheme, "=\"") {
+		return false
+	}
+
+	return strings.Contains(fields[1], "=")
+}
+
+// parseOne parses a single "scheme param=\"value\", param2=\"value2\"" challenge.
+func parseOne(s string) (Challenge, bool) {
+	s = strings.TrimSpace(s)
+
+	schemeEnd := strings.IndexByte(s, ' ')
+	if schemeEnd < 0 {
+		return Challenge{}, false
+	}
+
+	scheme := strings.ToLower(s[:schemeEnd])
+	rest := s[schemeEnd+1:]
+
+	params := parseParams(rest)
+
+	return Challenge{
+		Scheme:  scheme,
+		Realm:   params["realm"],
+		Service: params["service"],
+		Scope:   params["scope"],
+		Params:  params,
+	}, true
+}
+
+// parseParams parses a comma-separated list of key="value" (or key=value)
+// pairs, honoring quoted commas within a value.
+func parseParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for _, pair := range splitParams(s) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(pair, '=')
+		if eq < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(pair[:eq]))
+		val := strings.TrimSpace(pair[eq+1:])
+		val = strings.Trim(val, `"`)
+
+		params[key] = val
+	}
+
+	return params
+}
+
+// splitParams splits a parameter list on top-level commas, ignoring commas
+// inside quoted values.
+func splitParams(s string) []string {
+	var (
+		parts    []string
+		buf      strings.Builder
+		inQuotes bool
+	)
+
+	for _, ch := range s {
+		switch {
+		case ch == '"':
+			inQuotes = !inQuotes
+			buf.WriteRune(ch)
+		case ch == ',' && !inQuotes:
+			parts = append(parts, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteRune(ch)
+		}
+	}
+
+	parts = append(parts, buf.String())
+
+	return parts
+}
+
+// Bearer finds the first "Bearer" challenge among challenges, if any.
+func Bearer(challenges []Challenge) (Challenge, bool) {
+	for _, c := range challenges {
+		if c.Scheme == "bearer" {
+			return c, true
+		}
+	}
+
+	return Challenge{}, false
+}
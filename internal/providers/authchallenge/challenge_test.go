@@ -0,0 +1,92 @@
+package authchallenge
+
+import "testing"
+
+func TestParseAll_SingleBearerChallenge(t *testing.T) {
+	t.Parallel()
+
+	header := `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repo:pull,push"`
+
+	challenges := ParseAll(header)
+	if len(challenges) != 1 {
+		t.Fatalf("expected 1 challenge, got %d", len(challenges))
+	}
+
+	c := challenges[0]
+	if c.Scheme != "bearer" {
+		t.Errorf("expected scheme %q, got %q", "bearer", c.Scheme)
+	}
+
+	if c.Realm != "https://auth.example.com/token" {
+		t.Errorf("unexpected realm: %q", c.Realm)
+	}
+
+	if c.Service != "registry.example.com" {
+		t.Errorf("unexpected service: %q", c.Service)
+	}
+
+	if c.Scope != "repo:pull,push" {
+		t.Errorf("expected quoted comma to stay within scope, got: %q", c.Scope)
+	}
+}
+
+func TestParseAll_CaseInsensitiveScheme(t *testing.T) {
+	t.Parallel()
+
+	challenges := ParseAll(`BEARER realm="https://auth.example.com/token"`)
+	if len(challenges) != 1 || challenges[0].Scheme != "bearer" {
+		t.Fatalf("expected lowercased bearer scheme, got %+v", challenges)
+	}
+}
+
+func TestParseAll_MultipleChallenges(t *testing.T) {
+	t.Parallel()
+
+	header := `Basic realm="fallback", Bearer realm="https://auth.example.com/token",service="svc"`
+
+	challenges := ParseAll(header)
+	if len(challenges) != 2 {
+		t.Fatalf("expected 2 challenges, got %d: %+v", len(challenges), challenges)
+	}
+
+	if challenges[0].Scheme != "basic" || challenges[0].Realm != "fallback" {
+		t.Errorf("unexpected first challenge: %+v", challenges[0])
+	}
+
+	if challenges[1].Scheme != "bearer" || challenges[1].Service != "svc" {
+		t.Errorf("unexpected second challenge: %+v", challenges[1])
+	}
+}
+
+func TestBearer_FindsBearerAmongChallenges(t *testing.T) {
+	t.Parallel()
+
+	challenges := ParseAll(`Basic realm="fallback", Bearer realm="https://auth.example.com/token"`)
+
+	bearer, ok := Bearer(challenges)
+	if !ok {
+		t.Fatal("expected to find a bearer challenge")
+	}
+
+	if bearer.Realm != "https://auth.example.com/token" {
+		t.Errorf("unexpected bearer realm: %q", bearer.Realm)
+	}
+}
+
+func TestBearer_NotFound(t *testing.T) {
+	t.Parallel()
+
+	challenges := ParseAll(`Basic realm="fallback"`)
+
+	if _, ok := Bearer(challenges); ok {
+		t.Error("expected no bearer challenge to be found")
+	}
+}
+
+func TestParseAll_EmptyHeader(t *testing.T) {
+	t.Parallel()
+
+	if challenges := ParseAll(""); len(challenges) != 0 {
+		t.Errorf("expected no challenges for empty header, got %+v", challenges)
+	}
+}
@@ -0,0 +1,11 @@
+package providers
+
+// Model describes a single model advertised by a Provider, shaped like the
+// OpenAI-style /v1/models list entry so it can be returned to clients as-is.
+type Model struct {
+	ID       string `json:"id"`
+	Object   string `json:"object"`
+	Created  int64  `json:"created"`
+	OwnedBy  string `json:"owned_by"`
+	Provider string `json:"provider"`
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+// OAuthTokenFetcher is a TokenFetcher that exchanges a refresh token (or, if
+// RefreshToken is empty, client credentials) for a bearer token against a
+// fixed OAuth2 token endpoint. challenge.Realm, if set, overrides Endpoint,
+// matching how a Bearer challenge can point at a per-request token endpoint
+// (e.g. Vertex or Bedrock-style backends).
+type OAuthTokenFetcher struct {
+	// Endpoint is the token endpoint URL used when the challenge doesn't
+	// carry its own realm.
+	Endpoint string
+	// ClientID and ClientSecret authenticate the token request.
+	ClientID     string
+	ClientSecret string
+	// RefreshToken, when set, requests a "refresh_token" grant; otherwise a
+	// "client_credentials" grant is requested.
+	RefreshToken string
+
+	// HTTPClient performs the token request. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// tokenResponse is the subset of RFC 6749 section 5.1's token response this
+// fetcher understands.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Fetch performs the token endpoint exchange and returns the resulting
+// bearer token and its expiry.
+func (f *OAuthTokenFetcher) Fetch(ctx context.Context, challenge authchallenge.Challenge) (string, time.Time, error) {
+	endpoint := f.Endpoint
+	if challenge.Realm != "" {
+		endpoint = challenge.Realm
+	}
+
+	if endpoint == "" {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: no token endpoint configured")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", f.ClientID)
+	form.Set("client_secret", f.ClientSecret)
+
+	if f.RefreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", f.RefreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	if challenge.Scope != "" {
+		form.Set("scope", challenge.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // best-effort drain
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: decoding response: %w", err)
+	}
+
+	if tr.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("oauth token fetcher: token endpoint response had no access_token")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	if tr.ExpiresIn <= 0 {
+		expiresAt = time.Now().Add(5 * time.Minute)
+	}
+
+	return tr.AccessToken, expiresAt, nil
+}
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+func TestOAuthTokenFetcher_FetchReturnsAccessToken(t *testing.T) {
+	t.Parallel()
+
+	var gotGrantType, gotRefreshToken string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm failed: %v", err)
+		}
+
+		gotGrantType = r.PostForm.Get("grant_type")
+		gotRefreshToken = r.PostForm.Get("refresh_token")
+
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck // test response write
+		w.Write([]byte(`{"access_token":"tok-123","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	fetcher := &OAuthTokenFetcher{Endpoint: ts.URL, ClientID: "id", ClientSecret: "secret", RefreshToken: "rt-1"}
+
+	token, expiresAt, err := fetcher.Fetch(context.Background(), authchallenge.Challenge{})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if token != "tok-123" {
+		t.Errorf("expected token %q, got %q", "tok-123", token)
+	}
+
+	if expiresAt.IsZero() {
+		t.Error("expected a non-zero expiry")
+	}
+
+	if gotGrantType != "refresh_token" {
+		t.Errorf("expected grant_type=refresh_token, got %q", gotGrantType)
+	}
+
+	if gotRefreshToken != "rt-1" {
+		t.Errorf("expected refresh_token=rt-1, got %q", gotRefreshToken)
+	}
+}
+
+func TestOAuthTokenFetcher_ChallengeRealmOverridesEndpoint(t *testing.T) {
+	t.Parallel()
+
+	called := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.Header().Set("Content-Type", "application/json")
+		//nolint:errcheck // test response write
+		w.Write([]byte(`{"access_token":"tok-from-realm","expires_in":60}`))
+	}))
+	defer ts.Close()
+
+	fetcher := &OAuthTokenFetcher{Endpoint: "http://unused.invalid"}
+
+	token, _, err := fetcher.Fetch(context.Background(), authchallenge.Challenge{Realm: ts.URL})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if !called {
+		t.Fatal("expected the challenge's realm to be used as the token endpoint")
+	}
+
+	if token != "tok-from-realm" {
+		t.Errorf("expected token %q, got %q", "tok-from-realm", token)
+	}
+}
+
+func TestOAuthTokenFetcher_NoEndpointErrors(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &OAuthTokenFetcher{}
+
+	if _, _, err := fetcher.Fetch(context.Background(), authchallenge.Challenge{}); err == nil {
+		t.Fatal("expected an error when no token endpoint is configured")
+	}
+}
+
+func TestOAuthTokenFetcher_NonOKStatusErrors(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	fetcher := &OAuthTokenFetcher{Endpoint: ts.URL}
+
+	if _, _, err := fetcher.Fetch(context.Background(), authchallenge.Challenge{}); err == nil {
+		t.Fatal("expected an error for a non-200 token endpoint response")
+	}
+}
@@ -0,0 +1,339 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+// HealthCheckConfig configures the active health checker run by a Pool.
+type HealthCheckConfig struct {
+	// Interval is the time between probes of each provider. Zero disables active health checking.
+	Interval time.Duration
+	// Timeout bounds a single probe request.
+	Timeout time.Duration
+	// Path is the endpoint probed on each provider. Defaults to "/v1/messages".
+	Path string
+	// UnhealthyThreshold is the number of consecutive failed probes before a provider is marked unhealthy.
+	UnhealthyThreshold int
+	// HealthyThreshold is the number of consecutive successful probes required to bring a provider back up.
+	HealthyThreshold int
+}
+
+// withDefaults returns a copy of cfg with zero-valued fields filled in.
+func (c HealthCheckConfig) withDefaults() HealthCheckConfig {
+	if c.Path == "" {
+		c.Path = "/v1/messages"
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	if c.UnhealthyThreshold <= 0 {
+		c.UnhealthyThreshold = 3
+	}
+
+	if c.HealthyThreshold <= 0 {
+		c.HealthyThreshold = 2
+	}
+
+	return c
+}
+
+// FailoverConfig controls retry behavior across the pool when a provider fails a proxied request.
+type FailoverConfig struct {
+	// MaxRetries is the maximum number of additional providers to try after the first failure.
+	MaxRetries int
+	// Backoff is the delay before retrying against the next healthy provider.
+	Backoff time.Duration
+}
+
+// providerState tracks the health of a single pooled provider.
+type providerState struct {
+	provider             Provider
+	key                  string
+	modelRewrite         map[string]string
+	mu                   sync.Mutex
+	healthy              bool
+	consecutiveFailures  int
+	consecutiveSuccesses int
+	nextProbeAt          time.Time
+}
+
+// Entry pairs a Provider with the backend key used to authenticate against
+// it, and an optional per-provider model rewrite table (e.g. mapping
+// "claude-3-5-sonnet-latest" to an OpenRouter or Bedrock model id).
+type Entry struct {
+	Provider     Provider
+	Key          string
+	ModelRewrite map[string]string
+}
+
+// Pool holds multiple Provider implementations and tracks their health so the
+// proxy handler can iterate healthy providers in order and fail over on error.
+type Pool struct {
+	states []*providerState
+	hcCfg  HealthCheckConfig
+	foCfg  FailoverConfig
+	client *http.Client
+}
+
+// NewPool creates a Pool over the given entries in priority order.
+// All providers start marked healthy; the health checker, once started,
+// will mark them down after consecutive probe failures.
+func NewPool(entries []Entry, hcCfg HealthCheckConfig, foCfg FailoverConfig) *Pool {
+	states := make([]*providerState, 0, len(entries))
+	for _, e := range entries {
+		states = append(states, &providerState{
+			provider:     e.Provider,
+			key:          e.Key,
+			modelRewrite: e.ModelRewrite,
+			healthy:      true,
+		})
+	}
+
+	return &Pool{
+		states: states,
+		hcCfg:  hcCfg.withDefaults(),
+		foCfg:  foCfg,
+		client: &http.Client{Timeout: hcCfg.withDefaults().Timeout},
+	}
+}
+
+// Healthy returns the pooled entries currently considered healthy, in priority order.
+func (p *Pool) Healthy() []Entry {
+	entries := make([]Entry, 0, len(p.states))
+
+	for _, s := range p.states {
+		s.mu.Lock()
+		healthy := s.healthy
+		s.mu.Unlock()
+
+		if healthy {
+			entries = append(entries, Entry{Provider: s.provider, Key: s.key, ModelRewrite: s.modelRewrite})
+		}
+	}
+
+	return entries
+}
+
+// All returns every pooled entry regardless of health, in priority order.
+func (p *Pool) All() []Entry {
+	entries := make([]Entry, 0, len(p.states))
+	for _, s := range p.states {
+		entries = append(entries, Entry{Provider: s.provider, Key: s.key, ModelRewrite: s.modelRewrite})
+	}
+
+	return entries
+}
+
+// Failover returns the FailoverConfig the pool was constructed with.
+func (p *Pool) Failover() FailoverConfig {
+	return p.foCfg
+}
+
+// MarkResult records the outcome of a proxied request against name, updating
+// consecutive failure/success counters and flipping health state at the
+// configured thresholds. It is safe to call this outside of the background
+// health checker, e.g. from the handler's response path.
+func (p *Pool) MarkResult(name string, ok bool) {
+	for _, s := range p.states {
+		if s.provider.Name() != name {
+			continue
+		}
+
+		s.mu.Lock()
+		p.recordLocked(s, ok)
+		s.mu.Unlock()
+
+		return
+	}
+}
+
+func (p *Pool) recordLocked(s *providerState, ok bool) {
+	if ok {
+		s.consecutiveFailures = 0
+		s.consecutiveSuccesses++
+
+		if !s.healthy && s.consecutiveSuccesses >= p.hcCfg.HealthyThreshold {
+			s.healthy = true
+
+			log.Info().Str("provider", s.provider.Name()).Msg("provider marked healthy")
+		}
+
+		return
+	}
+
+	s.consecutiveSuccesses = 0
+	s.consecutiveFailures++
+
+	if s.healthy && s.consecutiveFailures >= p.hcCfg.UnhealthyThreshold {
+		s.healthy = false
+
+		log.Warn().Str("provider", s.provider.Name()).Int("consecutive_failures", s.consecutiveFailures).
+			Msg("provider marked unhealthy")
+	}
+}
+
+// StartHealthChecks runs active probes against every pooled provider on
+// hcCfg.Interval until ctx is canceled. It returns immediately if Interval is
+// zero, leaving all providers at their initial health state.
+func (p *Pool) StartHealthChecks(ctx context.Context) {
+	if p.hcCfg.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.hcCfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.probeAll(ctx)
+			}
+		}
+	}()
+}
+
+func (p *Pool) probeAll(ctx context.Context) {
+	now := time.Now()
+
+	for _, s := range p.states {
+		s.mu.Lock()
+		due := now.After(s.nextProbeAt)
+		s.mu.Unlock()
+
+		if !due {
+			continue
+		}
+
+		ok := p.probe(ctx, s)
+
+		s.mu.Lock()
+		p.recordLocked(s, ok)
+		s.nextProbeAt = time.Now().Add(p.hcCfg.Interval)
+		s.mu.Unlock()
+	}
+}
+
+// probe issues a single health check request against s, preferring HEAD and
+// falling back to a minimal POST body when the provider rejects it.
+func (p *Pool) probe(ctx context.Context, s *providerState) bool {
+	probeCtx, cancel := context.WithTimeout(ctx, p.hcCfg.Timeout)
+	defer cancel()
+
+	url := s.provider.BaseURL() + p.hcCfg.Path
+
+	resp, err := p.doAuthenticated(probeCtx, s, func() (*http.Request, error) {
+		return http.NewRequestWithContext(probeCtx, http.MethodHead, url, nil)
+	})
+	if err == nil {
+		defer resp.Body.Close() //nolint:errcheck // best-effort drain
+
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			return resp.StatusCode < http.StatusInternalServerError
+		}
+	}
+
+	// Provider doesn't support HEAD; retry with a minimal probe body.
+	resp, err = p.doAuthenticated(probeCtx, s, func() (*http.Request, error) {
+		body := bytes.NewReader([]byte(`{"model":"probe","max_tokens":1,"messages":[]}`))
+
+		req, err := http.NewRequestWithContext(probeCtx, http.MethodPost, url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range s.provider.ForwardHeaders(req.Header) {
+			req.Header[k] = v
+		}
+
+		return req, nil
+	})
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close() //nolint:errcheck // best-effort drain
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	// Any response other than a server-side failure means the upstream is reachable.
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// doAuthenticated builds a request via rebuild, authenticates it with s's
+// static key, and issues it. If the response is a 401 carrying a Bearer
+// WWW-Authenticate challenge and s's provider has a TokenSource configured
+// (see Provider.TokenSource), it obtains/refreshes a token and retries the
+// request once with an Authorization: Bearer header in place of the static
+// key. rebuild is called again for the retry since the first attempt may
+// have consumed the request body.
+//
+// doAuthenticated is currently only called from probe, i.e. the background
+// health checker: a token refresh here lets the checker notice a provider
+// is actually healthy again after a stale token, but does not retry a real
+// in-flight /v1/messages request on a 401 - that would need the same retry
+// wired into whatever forwards a client request to the chosen provider.
+func (p *Pool) doAuthenticated(ctx context.Context, s *providerState, rebuild func() (*http.Request, error)) (*http.Response, error) {
+	req, err := rebuild()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.provider.Authenticate(req, s.key); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	ts := s.provider.TokenSource()
+	if ts == nil {
+		return resp, nil
+	}
+
+	challenge, ok := authchallenge.Bearer(authchallenge.ParseAll(resp.Header.Get("WWW-Authenticate")))
+	if !ok {
+		return resp, nil
+	}
+
+	token, tokenErr := ts.Token(ctx, challenge)
+
+	resp.Body.Close() //nolint:errcheck // discarding the original 401 before retrying
+
+	if tokenErr != nil {
+		log.Warn().Err(tokenErr).Str("provider", s.provider.Name()).Msg("failed to refresh bearer token")
+		return resp, nil
+	}
+
+	retryReq, err := rebuild()
+	if err != nil {
+		return resp, nil
+	}
+
+	retryReq.Header.Set("Authorization", "Bearer "+token)
+
+	retryResp, err := p.client.Do(retryReq)
+	if err != nil {
+		return resp, nil
+	}
+
+	return retryResp, nil
+}
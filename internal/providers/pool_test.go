@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+func TestPool_HealthyInitiallyIncludesAll(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool([]Entry{
+		{Provider: NewAnthropicProvider("primary", "https://api.anthropic.com"), Key: "k1"},
+		{Provider: NewZAIProvider("fallback", ""), Key: "k2"},
+	}, HealthCheckConfig{}, FailoverConfig{MaxRetries: 1})
+
+	healthy := pool.Healthy()
+	if len(healthy) != 2 {
+		t.Fatalf("expected 2 healthy entries, got %d", len(healthy))
+	}
+}
+
+func TestPool_MarkResultFlipsUnhealthyAfterThreshold(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool([]Entry{
+		{Provider: NewAnthropicProvider("primary", "https://api.anthropic.com"), Key: "k1"},
+	}, HealthCheckConfig{UnhealthyThreshold: 2}, FailoverConfig{})
+
+	pool.MarkResult("primary", false)
+
+	if len(pool.Healthy()) != 1 {
+		t.Fatal("expected provider to still be healthy after one failure")
+	}
+
+	pool.MarkResult("primary", false)
+
+	if len(pool.Healthy()) != 0 {
+		t.Fatal("expected provider to be marked unhealthy after threshold failures")
+	}
+}
+
+func TestPool_MarkResultRecoversAfterThreshold(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool([]Entry{
+		{Provider: NewAnthropicProvider("primary", "https://api.anthropic.com"), Key: "k1"},
+	}, HealthCheckConfig{UnhealthyThreshold: 1, HealthyThreshold: 2}, FailoverConfig{})
+
+	pool.MarkResult("primary", false)
+
+	if len(pool.Healthy()) != 0 {
+		t.Fatal("expected provider to be unhealthy")
+	}
+
+	pool.MarkResult("primary", true)
+
+	if len(pool.Healthy()) != 0 {
+		t.Fatal("expected provider to still be unhealthy after a single success")
+	}
+
+	pool.MarkResult("primary", true)
+
+	if len(pool.Healthy()) != 1 {
+		t.Fatal("expected provider to recover after healthy threshold successes")
+	}
+}
+
+func TestPool_AllReturnsEveryEntryRegardlessOfHealth(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool([]Entry{
+		{Provider: NewAnthropicProvider("primary", "https://api.anthropic.com"), Key: "k1"},
+	}, HealthCheckConfig{UnhealthyThreshold: 1}, FailoverConfig{})
+
+	pool.MarkResult("primary", false)
+
+	if len(pool.All()) != 1 {
+		t.Fatal("expected All to return the provider even when unhealthy")
+	}
+}
+
+// stubTokenSource always returns token, recording how many times it was consulted.
+type stubTokenSource struct {
+	token string
+	calls int
+}
+
+func (s *stubTokenSource) Token(_ context.Context, _ authchallenge.Challenge) (string, error) {
+	s.calls++
+	return s.token, nil
+}
+
+func TestPool_ProbeRefreshesBearerTokenOn401(t *testing.T) {
+	t.Parallel()
+
+	var gotAuth string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://auth.example.com/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		gotAuth = r.Header.Get("Authorization")
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	provider := NewAnthropicProvider("primary", ts.URL)
+	tokenSource := &stubTokenSource{token: "fresh-token"}
+	provider.SetTokenSource(tokenSource)
+
+	pool := NewPool([]Entry{{Provider: provider, Key: "stale-key"}}, HealthCheckConfig{}, FailoverConfig{})
+
+	if !pool.probe(context.Background(), pool.states[0]) {
+		t.Fatal("expected probe to succeed after refreshing the bearer token")
+	}
+
+	if tokenSource.calls != 1 {
+		t.Errorf("expected the token source to be consulted once, got %d calls", tokenSource.calls)
+	}
+
+	if gotAuth != "Bearer fresh-token" {
+		t.Errorf("expected the retried request to carry the refreshed token, got %q", gotAuth)
+	}
+}
+
+func TestPool_ProbeWithNoTokenSourceDoesNotRetry(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no Authorization header without a configured TokenSource, got %q", r.Header.Get("Authorization"))
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="https://auth.example.com/token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	provider := NewAnthropicProvider("primary", ts.URL)
+	pool := NewPool([]Entry{{Provider: provider, Key: "stale-key"}}, HealthCheckConfig{}, FailoverConfig{})
+
+	pool.probe(context.Background(), pool.states[0])
+
+	// A single HEAD attempt: the provider has no TokenSource, so the 401 is
+	// returned as-is with no refresh retry.
+	if calls != 1 {
+		t.Errorf("expected exactly 1 request with no token source configured, got %d", calls)
+	}
+}
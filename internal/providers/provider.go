@@ -22,4 +22,15 @@ type Provider interface {
 
 	// SupportsStreaming indicates if the provider supports SSE streaming.
 	SupportsStreaming() bool
+
+	// TokenSource returns a TokenSource that can obtain/refresh a bearer
+	// token for this provider, or nil when the provider uses a static key
+	// (the default for every current provider). Pool consults this when a
+	// health probe fails with a 401 and a Bearer challenge (see
+	// Pool.doAuthenticated).
+	TokenSource() TokenSource
+
+	// ListModels returns the models this provider advertises, for the
+	// aggregated GET /v1/models endpoint.
+	ListModels() []Model
 }
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+// TokenSource obtains and refreshes a bearer token for a provider whose
+// upstream authenticates via OAuth-style challenge/token exchange (e.g.
+// Vertex or Bedrock-style backends) rather than a static API key.
+type TokenSource interface {
+	// Token returns a currently valid token for the given challenge,
+	// fetching or refreshing it as needed.
+	Token(ctx context.Context, challenge authchallenge.Challenge) (string, error)
+}
+
+// TokenFetcher performs the actual token endpoint exchange for a
+// CachingTokenSource. Implementations vary per credential type (client
+// secret, refresh token, service account key).
+type TokenFetcher interface {
+	// Fetch obtains a fresh token for the given challenge.
+	Fetch(ctx context.Context, challenge authchallenge.Challenge) (token string, expiresAt time.Time, err error)
+}
+
+// cacheKey identifies a cached token by the (realm, service, scope) tuple
+// from the challenge that produced it, matching how the docker distribution
+// client scopes its token cache.
+type cacheKey struct {
+	realm, service, scope string
+}
+
+type cacheEntry struct {
+	token     string
+	expiresAt time.Time
+}
+
+// CachingTokenSource wraps a TokenFetcher with an in-memory cache keyed by
+// (realm, service, scope), refreshing only once a cached token is within
+// refreshSkew of expiring.
+type CachingTokenSource struct {
+	fetcher     TokenFetcher
+	refreshSkew time.Duration
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+}
+
+// NewCachingTokenSource creates a CachingTokenSource backed by fetcher.
+// refreshSkew is the lead time before expiry at which a cached token is
+// treated as stale and refetched; a zero value defaults to 30 seconds.
+func NewCachingTokenSource(fetcher TokenFetcher, refreshSkew time.Duration) *CachingTokenSource {
+	if refreshSkew <= 0 {
+		refreshSkew = 30 * time.Second
+	}
+
+	return &CachingTokenSource{
+		fetcher:     fetcher,
+		refreshSkew: refreshSkew,
+		cache:       make(map[cacheKey]cacheEntry),
+	}
+}
+
+// Token returns a cached token for challenge's (realm, service, scope) when
+// it isn't near expiry, otherwise fetches and caches a new one.
+func (c *CachingTokenSource) Token(ctx context.Context, challenge authchallenge.Challenge) (string, error) {
+	key := cacheKey{realm: challenge.Realm, service: challenge.Service, scope: challenge.Scope}
+
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	c.mu.Unlock()
+
+	if ok && time.Until(entry.expiresAt) > c.refreshSkew {
+		return entry.token, nil
+	}
+
+	token, expiresAt, err := c.fetcher.Fetch(ctx, challenge)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cacheEntry{token: token, expiresAt: expiresAt}
+	c.mu.Unlock()
+
+	return token, nil
+}
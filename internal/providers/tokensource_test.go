@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/providers/authchallenge"
+)
+
+type fetchCounter struct {
+	calls int
+	token string
+	ttl   time.Duration
+}
+
+func (f *fetchCounter) Fetch(_ context.Context, _ authchallenge.Challenge) (string, time.Time, error) {
+	f.calls++
+	return f.token, time.Now().Add(f.ttl), nil
+}
+
+func TestCachingTokenSource_CachesUntilNearExpiry(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &fetchCounter{token: "tok-1", ttl: time.Hour}
+	src := NewCachingTokenSource(fetcher, time.Second)
+
+	challenge := authchallenge.Challenge{Realm: "https://auth", Service: "svc", Scope: "scope"}
+
+	for range 3 {
+		token, err := src.Token(context.Background(), challenge)
+		if err != nil {
+			t.Fatalf("Token failed: %v", err)
+		}
+
+		if token != "tok-1" {
+			t.Errorf("expected cached token, got %q", token)
+		}
+	}
+
+	if fetcher.calls != 1 {
+		t.Errorf("expected a single fetch for a long-lived token, got %d", fetcher.calls)
+	}
+}
+
+func TestCachingTokenSource_RefetchesNearExpiry(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &fetchCounter{token: "tok-1", ttl: 0}
+	src := NewCachingTokenSource(fetcher, time.Minute)
+
+	challenge := authchallenge.Challenge{Realm: "https://auth", Service: "svc", Scope: "scope"}
+
+	if _, err := src.Token(context.Background(), challenge); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	if _, err := src.Token(context.Background(), challenge); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	if fetcher.calls != 2 {
+		t.Errorf("expected a refetch when the cached token is within refreshSkew, got %d calls", fetcher.calls)
+	}
+}
+
+func TestCachingTokenSource_CacheKeyedByChallenge(t *testing.T) {
+	t.Parallel()
+
+	fetcher := &fetchCounter{token: "tok-1", ttl: time.Hour}
+	src := NewCachingTokenSource(fetcher, time.Second)
+
+	if _, err := src.Token(context.Background(), authchallenge.Challenge{Realm: "a", Scope: "x"}); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	if _, err := src.Token(context.Background(), authchallenge.Challenge{Realm: "b", Scope: "x"}); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	if fetcher.calls != 2 {
+		t.Errorf("expected distinct realms to be cached separately, got %d calls", fetcher.calls)
+	}
+}
@@ -17,9 +17,10 @@ const (
 // Z.AI (Zhipu AI) offers GLM models through an API that is compatible with Anthropic's
 // Messages API format, making it a drop-in replacement for cost optimization.
 type ZAIProvider struct {
-	name    string
-	baseURL string
-	models  []string
+	name        string
+	baseURL     string
+	models      []string
+	tokenSource TokenSource
 }
 
 // NewZAIProvider creates a new Z.AI provider instance.
@@ -93,6 +94,21 @@ func (p *ZAIProvider) SupportsStreaming() bool {
 	return true
 }
 
+// TokenSource returns the TokenSource set via SetTokenSource, or nil. Z.AI
+// normally authenticates with the static x-api-key set in Authenticate; a
+// TokenSource is only needed for deployments that challenge with a Bearer
+// token instead.
+func (p *ZAIProvider) TokenSource() TokenSource {
+	return p.tokenSource
+}
+
+// SetTokenSource attaches a TokenSource the proxy should consult when a
+// request to this provider is challenged with a Bearer WWW-Authenticate
+// header, instead of the static x-api-key.
+func (p *ZAIProvider) SetTokenSource(ts TokenSource) {
+	p.tokenSource = ts
+}
+
 // Owner returns the owner identifier for Z.AI.
 func (p *ZAIProvider) Owner() string {
 	return "zhipu"
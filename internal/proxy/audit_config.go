@@ -0,0 +1,49 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/omarluq/cc-relay/internal/audit"
+	"github.com/omarluq/cc-relay/internal/config"
+)
+
+// newAuditLogger builds the audit.Sink described by cfg and wraps it in an
+// audit.Logger applying cfg's redaction policy.
+func newAuditLogger(cfg *config.AuditConfig) (*audit.Logger, error) {
+	sink, err := newAuditSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := audit.RedactionPolicy{
+		Mode:      audit.RedactionMode(cfg.RedactionMode),
+		KeepChars: cfg.RedactionKeepChars,
+	}
+
+	return audit.NewLogger(sink, policy), nil
+}
+
+func newAuditSink(cfg *config.AuditConfig) (audit.Sink, error) {
+	switch cfg.Sink {
+	case "file":
+		sink, err := audit.NewFileSink(cfg.Path, cfg.MaxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log file %q: %w", cfg.Path, err)
+		}
+
+		return sink, nil
+	case "syslog":
+		sink, err := audit.NewSyslogSink(cfg.SyslogTag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+
+		return sink, nil
+	case "stdout", "":
+		return audit.NewWriterSink(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Sink)
+	}
+}
@@ -0,0 +1,248 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/audit"
+	"github.com/omarluq/cc-relay/internal/metrics"
+)
+
+// maxAuditBodyCaptureBytes bounds how much of a request/response body
+// AuditMiddleware buffers for Record.Body, regardless of RedactionPolicy.
+// This keeps memory use flat for large or streamed payloads; RedactionHash
+// and RedactionKeepEdges still produce a meaningful result over a truncated
+// prefix, and RedactionDrop never persists the captured bytes at all.
+const maxAuditBodyCaptureBytes = 64 * 1024
+
+// AuditMiddleware emits one audit.Record per request to logger once the
+// response completes, using the provider/model recorded in the routing
+// decision (see withRoutingSlot) and token counts parsed from the response
+// body, whether it was a single JSON reply or a streamed SSE response. It
+// also feeds reg.ObserveTokenUsage with those same counts - the only place
+// in the middleware chain that actually parses them out of the response.
+// Record.Body carries up to maxAuditBodyCaptureBytes of the request body
+// followed by the response body, before Logger.Log applies the configured
+// RedactionPolicy.
+func AuditMiddleware(logger *audit.Logger, reg *metrics.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqBody := captureRequestBody(r)
+			rec := &auditRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(rec, r)
+
+			decision, _ := GetRoutingDecision(r.Context())
+			usage := rec.usage()
+
+			if decision.Provider != "" {
+				reg.ObserveTokenUsage(decision.Provider, decision.Model, usage)
+			}
+
+			logger.Log(audit.Record{
+				Stage:             audit.StageResponseComplete,
+				Timestamp:         start,
+				RequestID:         GetRequestID(r.Context()),
+				KeyID:             GetSubject(r.Context()),
+				SourceIP:          sourceIP(r),
+				Method:            r.Method,
+				Path:              r.URL.Path,
+				Provider:          decision.Provider,
+				Model:             decision.Model,
+				InputTokens:       usage.InputTokens,
+				OutputTokens:      usage.OutputTokens,
+				UpstreamStatus:    rec.statusCode,
+				UpstreamLatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+				TotalBytes:        rec.bytesWritten,
+				Body:              combineBody(reqBody, rec.bodyCapture.String()),
+			})
+		})
+	}
+}
+
+// captureRequestBody reads up to maxAuditBodyCaptureBytes of r.Body for
+// audit purposes and replaces r.Body with a reader that replays the captured
+// prefix followed by whatever of the body remains unread, so next's handler
+// still sees the complete, unmodified request body.
+func captureRequestBody(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+
+	captured, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBodyCaptureBytes))
+	if err != nil {
+		return ""
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(captured), r.Body),
+		Closer: r.Body,
+	}
+
+	return string(captured)
+}
+
+// combineBody joins captured request/response text into the single string
+// carried by Record.Body, omitting either half when empty.
+func combineBody(reqBody, respBody string) string {
+	switch {
+	case reqBody == "" && respBody == "":
+		return ""
+	case reqBody == "":
+		return respBody
+	case respBody == "":
+		return reqBody
+	default:
+		return reqBody + "\n" + respBody
+	}
+}
+
+// sourceIP extracts the client IP from r.RemoteAddr, falling back to the raw
+// value if it isn't a host:port pair.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// auditRecorder wraps http.ResponseWriter to capture the status code, total
+// bytes written, a bounded prefix of the body for Record.Body, and token
+// usage parsed from the response as it streams through. SSE responses are
+// parsed line by line; a response with no newline (a single JSON reply) is
+// held in full in buf until usage parses it.
+type auditRecorder struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+	buf          bytes.Buffer
+	bodyCapture  bytes.Buffer
+	tokenUsage   metrics.TokenUsage
+}
+
+func (a *auditRecorder) WriteHeader(code int) {
+	a.statusCode = code
+	a.ResponseWriter.WriteHeader(code)
+}
+
+func (a *auditRecorder) Write(p []byte) (int, error) {
+	n, err := a.ResponseWriter.Write(p)
+	a.bytesWritten += int64(n)
+	a.consume(p[:n])
+
+	if remaining := maxAuditBodyCaptureBytes - a.bodyCapture.Len(); remaining > 0 {
+		if len(p) > remaining {
+			p = p[:remaining]
+		}
+
+		a.bodyCapture.Write(p)
+	}
+
+	return n, err
+}
+
+// consume scans newly-written bytes for complete SSE lines, extracting
+// usage events as they arrive. Any trailing partial line is held in a.buf
+// until more data completes it, or until usage() flushes it at the end.
+func (a *auditRecorder) consume(p []byte) {
+	a.buf.Write(p)
+
+	for {
+		line, err := a.buf.ReadBytes('\n')
+		if len(line) == 0 {
+			return
+		}
+
+		if err != nil {
+			// No newline yet; this is a partial line. Put it back and wait
+			// for the rest to arrive on a later Write.
+			a.buf.Write(line)
+			return
+		}
+
+		if usage, ok := ParseUsageEvent(line); ok {
+			a.tokenUsage = mergeTokenUsage(a.tokenUsage, usage)
+		}
+	}
+}
+
+// usage finalizes token accounting, parsing any buffered remainder as either
+// a trailing (unterminated) SSE line or, for non-streaming responses, the
+// whole body as a single JSON object with a top-level "usage" field.
+func (a *auditRecorder) usage() metrics.TokenUsage {
+	if a.buf.Len() == 0 {
+		return a.tokenUsage
+	}
+
+	remaining := a.buf.Bytes()
+
+	if usage, ok := ParseUsageEvent(remaining); ok {
+		a.tokenUsage = mergeTokenUsage(a.tokenUsage, usage)
+		return a.tokenUsage
+	}
+
+	if usage, ok := parsePlainUsage(remaining); ok {
+		a.tokenUsage = mergeTokenUsage(a.tokenUsage, usage)
+	}
+
+	return a.tokenUsage
+}
+
+// parsePlainUsage extracts a top-level "usage" object from a non-streaming
+// JSON response body, the shape Anthropic's non-streamed /v1/messages
+// replies use.
+func parsePlainUsage(body []byte) (metrics.TokenUsage, bool) {
+	var payload struct {
+		Usage *struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Usage == nil {
+		return metrics.TokenUsage{}, false
+	}
+
+	return metrics.TokenUsage{
+		InputTokens:      payload.Usage.InputTokens,
+		OutputTokens:     payload.Usage.OutputTokens,
+		CacheReadTokens:  payload.Usage.CacheReadInputTokens,
+		CacheWriteTokens: payload.Usage.CacheCreationInputTokens,
+	}, true
+}
+
+// mergeTokenUsage overlays non-zero fields from update onto base. Anthropic
+// SSE usage fields are cumulative as of the event that reported them, so the
+// latest non-zero value for each field is the correct running total.
+func mergeTokenUsage(base, update metrics.TokenUsage) metrics.TokenUsage {
+	if update.InputTokens > 0 {
+		base.InputTokens = update.InputTokens
+	}
+
+	if update.OutputTokens > 0 {
+		base.OutputTokens = update.OutputTokens
+	}
+
+	if update.CacheReadTokens > 0 {
+		base.CacheReadTokens = update.CacheReadTokens
+	}
+
+	if update.CacheWriteTokens > 0 {
+		base.CacheWriteTokens = update.CacheWriteTokens
+	}
+
+	return base
+}
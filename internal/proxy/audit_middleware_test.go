@@ -0,0 +1,212 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/audit"
+	"github.com/omarluq/cc-relay/internal/metrics"
+)
+
+// sha256Hex returns the lowercase hex-encoded SHA-256 digest of s, matching
+// the format audit.Redact produces under RedactionHash.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordingAuditSink captures every Record written to it, for assertions.
+type recordingAuditSink struct {
+	records []audit.Record
+}
+
+func (s *recordingAuditSink) Write(rec audit.Record) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func TestAuditMiddleware_RecordsRoutingDecisionAndSubject(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingAuditSink{}
+	logger := audit.NewLogger(sink, audit.RedactionPolicy{Mode: audit.RedactionDrop})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddRoutingDecision(r.Context(), Decision{Provider: "anthropic-primary", Model: "claude-3-5-sonnet"})
+		w.WriteHeader(http.StatusOK)
+		//nolint:errcheck // test response body
+		w.Write([]byte(`{"type":"message"}`))
+	})
+
+	handler := LoggingMiddleware()(AuditMiddleware(logger, metrics.New())(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	req = req.WithContext(AddSubject(req.Context(), "key:abcd1234"))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if len(sink.records) != 1 {
+		t.Fatalf("expected exactly one audit record, got %d", len(sink.records))
+	}
+
+	got := sink.records[0]
+	if got.Provider != "anthropic-primary" || got.Model != "claude-3-5-sonnet" {
+		t.Errorf("expected the routing decision to be recorded, got provider=%q model=%q", got.Provider, got.Model)
+	}
+
+	if got.KeyID != "key:abcd1234" {
+		t.Errorf("expected the authenticated subject to be recorded as KeyID, got %q", got.KeyID)
+	}
+
+	if got.UpstreamStatus != http.StatusOK {
+		t.Errorf("expected status 200 recorded, got %d", got.UpstreamStatus)
+	}
+}
+
+func TestAuditMiddleware_StreamingResponseRecordsFinalTokenCounts(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingAuditSink{}
+	logger := audit.NewLogger(sink, audit.RedactionPolicy{Mode: audit.RedactionDrop})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddRoutingDecision(r.Context(), Decision{Provider: "anthropic-primary", Model: "claude-3-5-sonnet"})
+
+		events := []string{
+			`event: message_start` + "\n",
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":42,"output_tokens":1}}}` + "\n\n",
+			`event: message_delta` + "\n",
+			`data: {"type":"message_delta","usage":{"output_tokens":17}}` + "\n\n",
+		}
+
+		for _, e := range events {
+			fmt.Fprint(w, e)
+		}
+	})
+
+	handler := LoggingMiddleware()(AuditMiddleware(logger, metrics.New())(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if len(sink.records) != 1 {
+		t.Fatalf("expected exactly one audit record, got %d", len(sink.records))
+	}
+
+	got := sink.records[0]
+	if got.InputTokens != 42 {
+		t.Errorf("expected input_tokens 42 from message_start, got %d", got.InputTokens)
+	}
+
+	if got.OutputTokens != 17 {
+		t.Errorf("expected output_tokens 17 (the latest cumulative total from message_delta), got %d", got.OutputTokens)
+	}
+}
+
+func TestAuditMiddleware_NeverLeaksBodyIntoRecord(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingAuditSink{}
+	logger := audit.NewLogger(sink, audit.RedactionPolicy{Mode: audit.RedactionDrop})
+
+	const secret = "sk-super-secret-upstream-response"
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		//nolint:errcheck // test response body
+		w.Write([]byte(secret))
+	})
+
+	handler := LoggingMiddleware()(AuditMiddleware(logger, metrics.New())(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Body.String() != secret {
+		t.Fatalf("expected the client to still receive the upstream body unchanged, got %q", rec.Body.String())
+	}
+
+	if strings.Contains(sink.records[0].Body, secret) {
+		t.Error("expected the audit record to never embed the raw response body")
+	}
+}
+
+func TestAuditMiddleware_HashRedactionCoversRealCapturedBody(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingAuditSink{}
+	logger := audit.NewLogger(sink, audit.RedactionPolicy{Mode: audit.RedactionHash})
+
+	const reqBody = `{"model":"claude-3-5-sonnet","messages":[]}`
+	const respBody = `{"type":"message","usage":{"input_tokens":1,"output_tokens":1}}`
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("next handler failed to read request body: %v", err)
+		}
+
+		if string(body) != reqBody {
+			t.Fatalf("expected next handler to see the full request body, got %q", body)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		//nolint:errcheck // test response body
+		w.Write([]byte(respBody))
+	})
+
+	handler := LoggingMiddleware()(AuditMiddleware(logger, metrics.New())(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	wantHash := "sha256:" + sha256Hex(reqBody+"\n"+respBody)
+	if sink.records[0].Body != wantHash {
+		t.Errorf("expected the audit record to hold a hash of the real captured request+response body, got %q, want %q", sink.records[0].Body, wantHash)
+	}
+}
+
+func TestAuditMiddleware_KeepEdgesRedactionCoversRealCapturedBody(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingAuditSink{}
+	logger := audit.NewLogger(sink, audit.RedactionPolicy{Mode: audit.RedactionKeepEdges, KeepChars: 4})
+
+	const respBody = `{"type":"message","usage":{"input_tokens":1,"output_tokens":1}}`
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		//nolint:errcheck // test response body
+		w.Write([]byte(respBody))
+	})
+
+	handler := LoggingMiddleware()(AuditMiddleware(logger, metrics.New())(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	got := sink.records[0].Body
+	if !strings.HasPrefix(got, respBody[:4]) || !strings.HasSuffix(got, respBody[len(respBody)-4:]) {
+		t.Errorf("expected keep_edges to preserve the edges of the real captured response body, got %q", got)
+	}
+
+	if got == respBody {
+		t.Error("expected the middle of the real captured response body to be elided")
+	}
+}
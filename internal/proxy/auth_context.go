@@ -0,0 +1,46 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"context"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+// subjectCtxKey is an unexported type to avoid collisions with context keys from other packages.
+type subjectCtxKey struct{}
+
+// capabilitiesCtxKey is an unexported type to avoid collisions with context keys from other packages.
+type capabilitiesCtxKey struct{}
+
+// AddSubject annotates ctx with the authenticated principal (OIDC "sub",
+// client certificate CN, etc) so downstream middleware can label logs and
+// metrics without re-validating the request.
+func AddSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectCtxKey{}, subject)
+}
+
+// GetSubject returns the authenticated principal stored by AddSubject, or
+// "" if none was recorded.
+func GetSubject(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectCtxKey{}).(string)
+	return subject
+}
+
+// AddCapabilities annotates ctx with the authenticated key's capabilities,
+// so the Router can honor the key's DefaultProvider without re-validating
+// the request. A nil caps is a no-op.
+func AddCapabilities(ctx context.Context, caps *auth.KeyCapabilities) context.Context {
+	if caps == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, capabilitiesCtxKey{}, caps)
+}
+
+// GetCapabilities returns the capabilities stored by AddCapabilities, or nil
+// if none were recorded.
+func GetCapabilities(ctx context.Context) *auth.KeyCapabilities {
+	caps, _ := ctx.Value(capabilitiesCtxKey{}).(*auth.KeyCapabilities)
+	return caps
+}
@@ -0,0 +1,142 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+// enforceCapabilities checks r against caps, the scoped API key's
+// capabilities, tracking per-key request rate in limiters. On success it
+// returns "", true and leaves r.Body readable by the next handler (re-buffered
+// if it had to be consumed for a model check). On failure it returns the name
+// of the missing capability ("path", "rate_limit", or "model") and false.
+func enforceCapabilities(caps *auth.KeyCapabilities, limiters *keyLimiterSet, subject string, r *http.Request) (string, bool) {
+	if len(caps.Paths) > 0 && !pathAllowed(caps.Paths, r.URL.Path) {
+		return "path", false
+	}
+
+	if !limiters.allow(subject, caps.RPS) {
+		return "rate_limit", false
+	}
+
+	if len(caps.Models) > 0 {
+		model, err := peekRequestModel(r)
+		if err == nil && !modelAllowed(caps.Models, model) {
+			return "model", false
+		}
+	}
+
+	return "", true
+}
+
+// pathAllowed reports whether path is an exact match for one of the allowed paths.
+func pathAllowed(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// modelAllowed reports whether model has one of the allowed prefixes.
+func modelAllowed(prefixes []string, model string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// peekRequestModel reads the "model" field out of a JSON request body
+// without consuming it for downstream handlers: r.Body is replaced with a
+// fresh reader over the same bytes before returning.
+func peekRequestModel(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Model string `json:"model"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", err
+	}
+
+	return payload.Model, nil
+}
+
+// tokenBucket is a simple time-based token bucket limiting a single key to
+// rps requests per second, with a one-second burst.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rps    float64
+	tokens float64
+	last   time.Time
+}
+
+// Allow reports whether a request may proceed, consuming a token if so.
+func (b *tokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rps
+	b.last = now
+
+	if b.tokens > b.rps {
+		b.tokens = b.rps
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// keyLimiterSet tracks one tokenBucket per authenticated key subject.
+type keyLimiterSet struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newKeyLimiterSet creates an empty keyLimiterSet.
+func newKeyLimiterSet() *keyLimiterSet {
+	return &keyLimiterSet{buckets: make(map[string]*tokenBucket)}
+}
+
+// allow reports whether subject may make another request under its rps
+// limit. rps <= 0 means unlimited.
+func (s *keyLimiterSet) allow(subject string, rps float64) bool {
+	if rps <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	bucket, ok := s.buckets[subject]
+	if !ok {
+		bucket = &tokenBucket{rps: rps, tokens: rps, last: time.Now()}
+		s.buckets[subject] = bucket
+	}
+	s.mu.Unlock()
+
+	return bucket.Allow()
+}
@@ -0,0 +1,86 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+)
+
+func TestEnforceCapabilities_PathNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	caps := &auth.KeyCapabilities{Paths: []string{"/v1/messages"}}
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", http.NoBody)
+
+	missing, ok := enforceCapabilities(caps, newKeyLimiterSet(), "key:test", req)
+	if ok {
+		t.Fatal("expected path restriction to deny the request")
+	}
+
+	if missing != "path" {
+		t.Errorf("expected missing capability %q, got %q", "path", missing)
+	}
+}
+
+func TestEnforceCapabilities_ModelPrefixNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	caps := &auth.KeyCapabilities{Models: []string{"claude-3-haiku"}}
+	body := strings.NewReader(`{"model":"claude-3-5-sonnet"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", body)
+
+	missing, ok := enforceCapabilities(caps, newKeyLimiterSet(), "key:test", req)
+	if ok {
+		t.Fatal("expected model restriction to deny the request")
+	}
+
+	if missing != "model" {
+		t.Errorf("expected missing capability %q, got %q", "model", missing)
+	}
+}
+
+func TestEnforceCapabilities_AllowsMatchingModelAndPreservesBody(t *testing.T) {
+	t.Parallel()
+
+	caps := &auth.KeyCapabilities{Models: []string{"claude-3-haiku"}}
+	body := strings.NewReader(`{"model":"claude-3-haiku-20240307"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", body)
+
+	if _, ok := enforceCapabilities(caps, newKeyLimiterSet(), "key:test", req); !ok {
+		t.Fatal("expected matching model prefix to be allowed")
+	}
+
+	remaining, err := peekRequestModel(req)
+	if err != nil {
+		t.Fatalf("expected body to still be readable, got error: %v", err)
+	}
+
+	if remaining != "claude-3-haiku-20240307" {
+		t.Errorf("expected body to be preserved for downstream handlers, got %q", remaining)
+	}
+}
+
+func TestEnforceCapabilities_RateLimitExceeded(t *testing.T) {
+	t.Parallel()
+
+	caps := &auth.KeyCapabilities{RPS: 1}
+	limiters := newKeyLimiterSet()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+
+	if _, ok := enforceCapabilities(caps, limiters, "key:test", req); !ok {
+		t.Fatal("expected the first request within the burst to be allowed")
+	}
+
+	missing, ok := enforceCapabilities(caps, limiters, "key:test", req)
+	if ok {
+		t.Fatal("expected the second immediate request to exceed the rate limit")
+	}
+
+	if missing != "rate_limit" {
+		t.Errorf("expected missing capability %q, got %q", "rate_limit", missing)
+	}
+}
@@ -2,6 +2,7 @@
 package proxy
 
 import (
+	"context"
 	"crypto/sha256"
 	"crypto/subtle"
 	"net/http"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/omarluq/cc-relay/internal/auth"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/metrics"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 // AuthMiddleware creates middleware that validates x-api-key header.
@@ -46,19 +49,43 @@ func AuthMiddleware(expectedAPIKey string) func(http.Handler) http.Handler {
 }
 
 // MultiAuthMiddleware creates middleware supporting multiple authentication methods.
-// Supports both x-api-key and Authorization: Bearer token authentication.
-// If authConfig has no methods enabled, all requests pass through.
-func MultiAuthMiddleware(authConfig *config.AuthConfig) func(http.Handler) http.Handler {
+// Supports OIDC bearer JWTs, static bearer secrets, x-api-key authentication,
+// and any extra authenticators passed in (e.g. a CertAuthenticator when the
+// server requires client certificates). Extra authenticators are tried first.
+// If authConfig has no methods enabled and no extras are given, all requests
+// pass through.
+func MultiAuthMiddleware(authConfig *config.AuthConfig, extra ...auth.Authenticator) func(http.Handler) http.Handler {
 	// Build the authenticator chain based on config
-	var authenticators []auth.Authenticator
+	authenticators := append([]auth.Authenticator{}, extra...)
+
+	// OIDC bearer JWTs (checked right after any extras - most specific, and
+	// the discovery/JWKS fetch below is the only authenticator construction that can fail)
+	if authConfig.OIDC != nil && authConfig.OIDC.Issuer != "" {
+		oidcAuth, err := auth.NewOIDCAuthenticator(context.Background(), auth.OIDCConfig{
+			Issuer:              authConfig.OIDC.Issuer,
+			ClientID:            authConfig.OIDC.ClientID,
+			RequiredScopes:      authConfig.OIDC.RequiredScopes,
+			RequiredClaims:      authConfig.OIDC.RequiredClaims,
+			JWKSRefreshInterval: authConfig.OIDC.JWKSRefreshInterval,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("issuer", authConfig.OIDC.Issuer).Msg("failed to initialize OIDC authenticator")
+		} else {
+			authenticators = append(authenticators, oidcAuth)
+		}
+	}
 
-	// Bearer token auth (checked first as it's more specific)
+	// Static bearer secret (checked after OIDC)
 	if authConfig.AllowBearer {
 		authenticators = append(authenticators, auth.NewBearerAuthenticator(authConfig.BearerSecret))
 	}
 
-	// API key auth
-	if authConfig.APIKey != "" {
+	// API key auth: a scoped key set takes precedence over a single global
+	// key, so operators can hand out differently-capped keys per caller.
+	switch {
+	case len(authConfig.APIKeys) > 0:
+		authenticators = append(authenticators, auth.NewScopedAPIKeyAuthenticator(authConfig.APIKeys))
+	case authConfig.APIKey != "":
 		authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(authConfig.APIKey))
 	}
 
@@ -70,6 +97,7 @@ func MultiAuthMiddleware(authConfig *config.AuthConfig) func(http.Handler) http.
 	}
 
 	chainAuth := auth.NewChainAuthenticator(authenticators...)
+	limiters := newKeyLimiterSet()
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +108,11 @@ func MultiAuthMiddleware(authConfig *config.AuthConfig) func(http.Handler) http.
 					Str("auth_type", string(result.Type)).
 					Str("error", result.Error).
 					Msg("authentication failed")
+
+				if result.Type == auth.TypeOIDC {
+					w.Header().Set("WWW-Authenticate", auth.WWWAuthenticateHeader("invalid_token", result.Error))
+				}
+
 				WriteError(w, http.StatusUnauthorized, "authentication_error", result.Error)
 
 				return
@@ -88,7 +121,24 @@ func MultiAuthMiddleware(authConfig *config.AuthConfig) func(http.Handler) http.
 			zerolog.Ctx(r.Context()).Debug().
 				Str("auth_type", string(result.Type)).
 				Msg("authentication succeeded")
-			next.ServeHTTP(w, r)
+
+			if result.Capabilities != nil {
+				if missing, ok := enforceCapabilities(result.Capabilities, limiters, result.Subject, r); !ok {
+					zerolog.Ctx(r.Context()).Warn().
+						Str("subject", result.Subject).
+						Str("missing_capability", missing).
+						Msg("request denied by key capabilities")
+
+					WriteError(w, http.StatusForbidden, "permission_error", "key is not permitted: missing capability "+missing)
+
+					return
+				}
+			}
+
+			ctx := AddSubject(r.Context(), result.Subject)
+			ctx = metrics.WithAuthType(ctx, string(result.Type))
+			ctx = AddCapabilities(ctx, result.Capabilities)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -139,6 +189,12 @@ func LoggingMiddleware() func(http.Handler) http.Handler {
 				Str("req_id", shortID).
 				Msgf("%s %s", r.Method, r.URL.Path)
 
+			// Install a routing decision slot the handler can fill via
+			// AddRoutingDecision; see withRoutingSlot for why this can't be
+			// a plain context value.
+			ctx, _ := withRoutingSlot(r.Context())
+			r = r.WithContext(ctx)
+
 			// Serve request
 			next.ServeHTTP(wrapped, r)
 
@@ -146,13 +202,26 @@ func LoggingMiddleware() func(http.Handler) http.Handler {
 			duration := time.Since(start)
 			durationStr := formatDuration(duration)
 
-			logger := zerolog.Ctx(r.Context()).With().
+			loggerCtx := zerolog.Ctx(r.Context()).With().
 				Str("method", r.Method).
 				Str("path", r.URL.Path).
 				Int("status", wrapped.statusCode).
 				Str("duration", durationStr).
-				Str("req_id", shortID).
-				Logger()
+				Str("req_id", shortID)
+
+			if subject := GetSubject(r.Context()); subject != "" {
+				loggerCtx = loggerCtx.Str("sub", subject)
+			}
+
+			if decision, ok := GetRoutingDecision(r.Context()); ok {
+				loggerCtx = loggerCtx.
+					Str("chosen_provider", decision.Provider).
+					Str("strategy", string(decision.Strategy)).
+					Str("matched_by", decision.MatchedBy).
+					Float64("latency_ewma_ms", decision.LatencyEWMAMs)
+			}
+
+			logger := loggerCtx.Logger()
 
 			// Format completion message based on status
 			var statusMsg string
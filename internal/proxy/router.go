@@ -0,0 +1,310 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/omarluq/cc-relay/internal/metrics"
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+// DefaultProviderHeader is the request header a client can set to pick a
+// provider directly by name, bypassing model-based routing entirely.
+const DefaultProviderHeader = "x-cc-relay-provider"
+
+// Strategy names a provider-selection policy for the Router.
+type Strategy string
+
+const (
+	StrategyPriority     Strategy = "priority"
+	StrategyWeighted     Strategy = "weighted"
+	StrategyRoundRobin   Strategy = "round_robin"
+	StrategyLeastLatency Strategy = "least_latency"
+)
+
+// ErrNoHealthyProvider is returned by Router.Select when no healthy provider
+// advertises the requested model.
+var ErrNoHealthyProvider = errors.New("no healthy provider available for model")
+
+// RouterConfig configures a Router's selection policy.
+type RouterConfig struct {
+	// Strategy selects the dispatch policy. Defaults to StrategyPriority.
+	Strategy Strategy
+	// Weights maps provider name to its relative weight for StrategyWeighted.
+	// Providers absent from the map default to weight 1.
+	Weights map[string]int
+	// ModelAliases maps a requested model (supporting a trailing "*" glob)
+	// to the model ID actually sent upstream, e.g.
+	// "claude-3-5-sonnet-*" -> "glm-4.6".
+	ModelAliases map[string]string
+	// LatencyAlpha is the EWMA smoothing factor for StrategyLeastLatency,
+	// in (0, 1]; higher weights recent samples more heavily. Defaults to 0.3.
+	LatencyAlpha float64
+	// Metrics, if set, is fed every RecordLatency sample as
+	// ccrelay_upstream_latency_seconds - the real per-call backend latency,
+	// as opposed to MetricsMiddleware's full (and, for streaming responses,
+	// misleading) handler duration.
+	Metrics *metrics.Registry
+}
+
+// Decision records why a provider was selected, for request-scoped logging.
+type Decision struct {
+	Provider      string
+	Model         string
+	Strategy      Strategy
+	LatencyEWMAMs float64
+	// MatchedBy names how the provider was chosen: "header", "key_default",
+	// or "strategy" (the model/priority/weighted/round_robin/least_latency
+	// dispatch below).
+	MatchedBy string
+}
+
+// Router selects among a Pool's healthy providers per request, based on the
+// requested model and the configured Strategy.
+type Router struct {
+	pool *providers.Pool
+	cfg  RouterConfig
+
+	mu      sync.Mutex
+	rrIndex int
+	ewmaMs  map[string]float64
+}
+
+// NewRouter creates a Router over pool using cfg. A zero-value Strategy
+// defaults to priority (first healthy provider advertising the model, in
+// pool order).
+func NewRouter(pool *providers.Pool, cfg RouterConfig) *Router {
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyPriority
+	}
+
+	if cfg.LatencyAlpha <= 0 {
+		cfg.LatencyAlpha = 0.3
+	}
+
+	return &Router{pool: pool, cfg: cfg, ewmaMs: make(map[string]float64)}
+}
+
+// ResolveModel applies the configured model alias table to model, supporting
+// a trailing "*" glob on the alias key (e.g. "claude-3-5-sonnet-*"). It
+// returns model unchanged when no alias matches.
+func (r *Router) ResolveModel(model string) string {
+	if alias, ok := r.cfg.ModelAliases[model]; ok {
+		return alias
+	}
+
+	for pattern, alias := range r.cfg.ModelAliases {
+		if matched, _ := filepath.Match(pattern, model); matched {
+			return alias
+		}
+	}
+
+	return model
+}
+
+// Select picks a healthy provider for model, honoring (in priority order) a
+// DefaultProviderHeader override on req, the authenticated key's
+// auth.KeyCapabilities.DefaultProvider (see proxy.GetCapabilities), and
+// finally the configured Strategy among providers advertising model. The
+// returned Decision.Model is model after applying both the router's global
+// ModelAliases and the chosen entry's per-provider ModelRewrite table, ready
+// to send upstream. It returns ErrNoHealthyProvider if no healthy provider
+// matches.
+func (r *Router) Select(req *http.Request, model string) (providers.Entry, Decision, error) {
+	model = r.ResolveModel(model)
+
+	if name := req.Header.Get(DefaultProviderHeader); name != "" {
+		entry, ok := r.findHealthy(name)
+		if !ok {
+			return providers.Entry{}, Decision{}, fmt.Errorf("%w: header %q names unknown or unhealthy provider %q",
+				ErrNoHealthyProvider, DefaultProviderHeader, name)
+		}
+
+		return r.finalize(entry, model, "header")
+	}
+
+	if caps := GetCapabilities(req.Context()); caps != nil && caps.DefaultProvider != "" {
+		if entry, ok := r.findHealthy(caps.DefaultProvider); ok {
+			return r.finalize(entry, model, "key_default")
+		}
+	}
+
+	candidates := r.candidatesFor(model)
+	if len(candidates) == 0 {
+		return providers.Entry{}, Decision{}, ErrNoHealthyProvider
+	}
+
+	var chosen providers.Entry
+
+	switch r.cfg.Strategy {
+	case StrategyWeighted:
+		chosen = r.selectWeighted(candidates)
+	case StrategyRoundRobin:
+		chosen = r.selectRoundRobin(candidates)
+	case StrategyLeastLatency:
+		chosen = r.selectLeastLatency(candidates)
+	case StrategyPriority:
+		fallthrough
+	default:
+		chosen = candidates[0]
+	}
+
+	return r.finalize(chosen, model, "strategy")
+}
+
+// findHealthy returns the healthy pool entry named name, if any.
+func (r *Router) findHealthy(name string) (providers.Entry, bool) {
+	for _, e := range r.pool.Healthy() {
+		if e.Provider.Name() == name {
+			return e, true
+		}
+	}
+
+	return providers.Entry{}, false
+}
+
+// finalize applies entry's per-provider ModelRewrite table to model and
+// builds the Decision reported to the caller and to request-scoped logging.
+func (r *Router) finalize(entry providers.Entry, model, matchedBy string) (providers.Entry, Decision, error) {
+	resolvedModel := model
+	if rewritten, ok := entry.ModelRewrite[model]; ok {
+		resolvedModel = rewritten
+	}
+
+	r.mu.Lock()
+	latency := r.ewmaMs[entry.Provider.Name()]
+	r.mu.Unlock()
+
+	return entry, Decision{
+		Provider:      entry.Provider.Name(),
+		Model:         resolvedModel,
+		Strategy:      r.cfg.Strategy,
+		LatencyEWMAMs: latency,
+		MatchedBy:     matchedBy,
+	}, nil
+}
+
+// candidatesFor returns healthy pool entries advertising model (or all
+// healthy entries when model is empty or no provider advertises models).
+func (r *Router) candidatesFor(model string) []providers.Entry {
+	healthy := r.pool.Healthy()
+	if model == "" {
+		return healthy
+	}
+
+	var matches []providers.Entry
+
+	anyAdvertised := false
+
+	for _, e := range healthy {
+		models := e.Provider.ListModels()
+		if len(models) > 0 {
+			anyAdvertised = true
+		}
+
+		for _, m := range models {
+			if m.ID == model {
+				matches = append(matches, e)
+				break
+			}
+		}
+	}
+
+	if !anyAdvertised {
+		// No provider advertises a model list; fall back to routing by
+		// priority order alone.
+		return healthy
+	}
+
+	return matches
+}
+
+func (r *Router) selectWeighted(candidates []providers.Entry) providers.Entry {
+	total := 0
+
+	weights := make([]int, len(candidates))
+	for i, c := range candidates {
+		w := r.cfg.Weights[c.Provider.Name()]
+		if w <= 0 {
+			w = 1
+		}
+
+		weights[i] = w
+		total += w
+	}
+
+	pick := rand.Intn(total) //nolint:gosec // routing weight, not security-sensitive
+
+	for i, w := range weights {
+		pick -= w
+		if pick < 0 {
+			return candidates[i]
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+func (r *Router) selectRoundRobin(candidates []providers.Entry) providers.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	chosen := candidates[r.rrIndex%len(candidates)]
+	r.rrIndex++
+
+	return chosen
+}
+
+func (r *Router) selectLeastLatency(candidates []providers.Entry) providers.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	best := candidates[0]
+	bestLatency, bestSeen := r.ewmaMs[best.Provider.Name()]
+
+	for _, c := range candidates[1:] {
+		latency, seen := r.ewmaMs[c.Provider.Name()]
+		if !seen {
+			// No samples yet; prefer exploring it over a provider with a
+			// known-worse EWMA.
+			return c
+		}
+
+		// A never-recorded EWMA (e.g. candidates[0] is healthy but its
+		// calls always error, so RecordLatency never fires for it) must
+		// not silently act as a real 0ms and beat every other candidate
+		// forever - track "seen" explicitly instead.
+		if !bestSeen || latency < bestLatency {
+			best, bestLatency, bestSeen = c, latency, true
+		}
+	}
+
+	return best
+}
+
+// RecordLatency updates the EWMA of successful response latency for
+// provider, for use by StrategyLeastLatency. Call this from the handler's
+// response path after a successful (non-retried) upstream call.
+func (r *Router) RecordLatency(provider string, d time.Duration) {
+	ms := float64(d.Milliseconds())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.ewmaMs[provider]
+	if !ok {
+		r.ewmaMs[provider] = ms
+	} else {
+		r.ewmaMs[provider] = r.cfg.LatencyAlpha*ms + (1-r.cfg.LatencyAlpha)*current
+	}
+
+	if r.cfg.Metrics != nil {
+		r.cfg.Metrics.ObserveUpstreamLatency(provider, d)
+	}
+}
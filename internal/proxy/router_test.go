@@ -0,0 +1,270 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/omarluq/cc-relay/internal/auth"
+	"github.com/omarluq/cc-relay/internal/metrics"
+	"github.com/omarluq/cc-relay/internal/providers"
+)
+
+func newSelectRequest() *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/v1/messages", http.NoBody)
+}
+
+func newTestPool(t *testing.T) *providers.Pool {
+	t.Helper()
+
+	return providers.NewPool([]providers.Entry{
+		{Provider: providers.NewAnthropicProviderWithModels("primary", "https://api.anthropic.com", []string{"claude-3-5-sonnet"}), Key: "k1"},
+		{Provider: providers.NewZAIProviderWithModels("fallback", "", []string{"glm-4.6"}), Key: "k2"},
+	}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+}
+
+func TestRouter_PrioritySelectsFirstHealthy(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	entry, decision, err := router.Select(newSelectRequest(), "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "primary" {
+		t.Errorf("expected priority to pick primary, got %q", entry.Provider.Name())
+	}
+
+	if decision.Strategy != StrategyPriority {
+		t.Errorf("expected strategy in decision, got %q", decision.Strategy)
+	}
+}
+
+func TestRouter_SelectsByModel(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	entry, _, err := router.Select(newSelectRequest(), "glm-4.6")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "fallback" {
+		t.Errorf("expected model match to pick fallback, got %q", entry.Provider.Name())
+	}
+}
+
+func TestRouter_NoHealthyProviderForModel(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	_, _, err := router.Select(newSelectRequest(), "unknown-model")
+	if err != ErrNoHealthyProvider {
+		t.Fatalf("expected ErrNoHealthyProvider, got %v", err)
+	}
+}
+
+func TestRouter_ResolveModelAliasGlob(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{
+		ModelAliases: map[string]string{"claude-3-5-sonnet-*": "glm-4.6"},
+	})
+
+	if got := router.ResolveModel("claude-3-5-sonnet-20241022"); got != "glm-4.6" {
+		t.Errorf("expected glob alias to resolve, got %q", got)
+	}
+
+	if got := router.ResolveModel("untouched-model"); got != "untouched-model" {
+		t.Errorf("expected unaliased model to pass through, got %q", got)
+	}
+}
+
+func TestRouter_RoundRobinAlternates(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyRoundRobin})
+
+	first, _, err := router.Select(newSelectRequest(), "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	second, _, err := router.Select(newSelectRequest(), "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if first.Provider.Name() == second.Provider.Name() {
+		t.Errorf("expected round robin to alternate providers, got %q twice", first.Provider.Name())
+	}
+}
+
+func TestRouter_LeastLatencyPrefersLowerEWMA(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyLeastLatency})
+
+	router.RecordLatency("primary", 200_000_000) // 200ms
+	router.RecordLatency("fallback", 10_000_000) // 10ms
+
+	entry, decision, err := router.Select(newSelectRequest(), "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "fallback" {
+		t.Errorf("expected least_latency to pick fallback, got %q", entry.Provider.Name())
+	}
+
+	if decision.LatencyEWMAMs <= 0 {
+		t.Errorf("expected a positive latency_ewma_ms, got %f", decision.LatencyEWMAMs)
+	}
+}
+
+func TestRouter_LeastLatencyDoesNotTreatUnseenFirstCandidateAsZeroLatency(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyLeastLatency})
+
+	// "primary" is candidates[0] and never records a latency sample (e.g. it
+	// is healthy but always errors before RecordLatency is called), while
+	// "fallback" has a real, good EWMA. Unseen must not look like 0ms.
+	router.RecordLatency("fallback", 10_000_000) // 10ms
+
+	entry, _, err := router.Select(newSelectRequest(), "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "fallback" {
+		t.Errorf("expected least_latency to prefer the provider with a real EWMA over an unseen candidates[0], got %q", entry.Provider.Name())
+	}
+}
+
+func TestRouter_RecordLatencyFeedsConfiguredMetricsRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := metrics.New()
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyLeastLatency, Metrics: reg})
+
+	router.RecordLatency("primary", 150_000_000) // 150ms
+
+	scrapeReq := httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+	scrapeRec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(scrapeRec, scrapeReq)
+
+	body := scrapeRec.Body.String()
+	if !strings.Contains(body, `ccrelay_upstream_latency_seconds_count{provider="primary"} 1`) {
+		t.Errorf("expected RecordLatency to feed the configured registry, got: %s", body)
+	}
+}
+
+func TestRoutingDecisionContext_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx, _ := withRoutingSlot(context.Background())
+
+	if _, ok := GetRoutingDecision(ctx); ok {
+		t.Fatal("expected no decision before AddRoutingDecision")
+	}
+
+	AddRoutingDecision(ctx, Decision{Provider: "primary", Strategy: StrategyPriority})
+
+	decision, ok := GetRoutingDecision(ctx)
+	if !ok {
+		t.Fatal("expected a decision to be present")
+	}
+
+	if decision.Provider != "primary" {
+		t.Errorf("expected provider %q, got %q", "primary", decision.Provider)
+	}
+}
+
+func TestRouter_HeaderOverridesStrategy(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	req := newSelectRequest()
+	req.Header.Set(DefaultProviderHeader, "fallback")
+
+	entry, decision, err := router.Select(req, "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "fallback" {
+		t.Errorf("expected header override to pick fallback, got %q", entry.Provider.Name())
+	}
+
+	if decision.MatchedBy != "header" {
+		t.Errorf("expected MatchedBy %q, got %q", "header", decision.MatchedBy)
+	}
+}
+
+func TestRouter_HeaderNamingUnknownProviderErrors(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	req := newSelectRequest()
+	req.Header.Set(DefaultProviderHeader, "nonexistent")
+
+	if _, _, err := router.Select(req, ""); err == nil {
+		t.Fatal("expected an error for a header naming an unknown provider")
+	}
+}
+
+func TestRouter_KeyDefaultProviderAppliesWhenNoHeader(t *testing.T) {
+	t.Parallel()
+
+	router := NewRouter(newTestPool(t), RouterConfig{Strategy: StrategyPriority})
+
+	req := newSelectRequest()
+	ctx := AddCapabilities(req.Context(), &auth.KeyCapabilities{DefaultProvider: "fallback"})
+	req = req.WithContext(ctx)
+
+	entry, decision, err := router.Select(req, "")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if entry.Provider.Name() != "fallback" {
+		t.Errorf("expected key default to pick fallback, got %q", entry.Provider.Name())
+	}
+
+	if decision.MatchedBy != "key_default" {
+		t.Errorf("expected MatchedBy %q, got %q", "key_default", decision.MatchedBy)
+	}
+}
+
+func TestRouter_ModelRewriteAppliedToChosenEntry(t *testing.T) {
+	t.Parallel()
+
+	pool := providers.NewPool([]providers.Entry{
+		{
+			Provider:     providers.NewAnthropicProviderWithModels("primary", "https://api.anthropic.com", []string{"claude-3-5-sonnet"}),
+			Key:          "k1",
+			ModelRewrite: map[string]string{"claude-3-5-sonnet": "claude-3-5-sonnet-20241022"},
+		},
+	}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	router := NewRouter(pool, RouterConfig{Strategy: StrategyPriority})
+
+	_, decision, err := router.Select(newSelectRequest(), "claude-3-5-sonnet")
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+
+	if decision.Model != "claude-3-5-sonnet-20241022" {
+		t.Errorf("expected rewritten model, got %q", decision.Model)
+	}
+}
@@ -2,36 +2,84 @@
 package proxy
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"github.com/omarluq/cc-relay/internal/auth"
 	"github.com/omarluq/cc-relay/internal/config"
+	"github.com/omarluq/cc-relay/internal/metrics"
 	"github.com/omarluq/cc-relay/internal/providers"
 )
 
 // SetupRoutes creates the HTTP handler with all routes configured.
 // Routes:
-//   - POST /v1/messages - Proxy to backend provider (with auth if configured)
+//   - POST /v1/messages - Proxy to backend provider pool (with auth if configured)
+//   - GET /v1/models - Aggregated model list across every enabled provider (no auth required)
 //   - GET /health - Health check endpoint (no auth required)
-func SetupRoutes(cfg *config.Config, provider providers.Provider, providerKey string) (http.Handler, error) {
+//   - GET /metrics - Prometheus metrics (no auth required)
+//
+// The handler iterates pool.Healthy() in priority order and fails over to the
+// next healthy provider on a 5xx or connection error, as long as no bytes of a
+// streaming response have been written yet. Among healthy providers advertising
+// the requested model, the router dispatches per cfg.Router.Strategy.
+func SetupRoutes(cfg *config.Config, pool *providers.Pool) (http.Handler, error) {
 	mux := http.NewServeMux()
 
+	reg := metrics.New()
+
+	router := NewRouter(pool, RouterConfig{
+		Strategy:     Strategy(cfg.Router.Strategy),
+		Weights:      cfg.Router.Weights,
+		ModelAliases: cfg.Router.ModelAliases,
+		Metrics:      reg,
+	})
+
 	// Create proxy handler
-	handler, err := NewHandler(provider, providerKey)
+	handler, err := NewHandler(pool, router)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create handler: %w", err)
 	}
 
+	for _, e := range pool.All() {
+		reg.SetProviderUp(e.Provider.Name(), true)
+	}
+
 	// Apply middleware in order:
 	// 1. RequestIDMiddleware (first - generates ID)
 	// 2. LoggingMiddleware (second - logs with ID)
-	// 3. AuthMiddleware (third - auth logs include ID)
-	// 4. Handler
+	// 3. MetricsMiddleware (third - records provider/model/status labels)
+	// 4. AuthMiddleware (fourth - auth logs include ID)
+	// 5. AuditMiddleware (fifth - records subject and routing decision)
+	// 6. Handler
 	var messagesHandler http.Handler = handler
-	if cfg.Server.APIKey != "" {
+
+	if cfg.Server.Audit != nil && cfg.Server.Audit.Enabled {
+		auditLogger, err := newAuditLogger(cfg.Server.Audit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create audit logger: %w", err)
+		}
+
+		messagesHandler = AuditMiddleware(auditLogger, reg)(messagesHandler)
+	}
+
+	switch {
+	case cfg.Auth != nil:
+		var extra []auth.Authenticator
+		if cfg.Server.TLS != nil && ClientAuthType(cfg.Server.TLS.ClientAuth) == ClientAuthRequireAndVerify {
+			if len(cfg.Server.TLS.ClientCertCapabilities) > 0 {
+				extra = append(extra, auth.NewScopedCertAuthenticator(cfg.Server.TLS.ClientCertCapabilities))
+			} else {
+				extra = append(extra, auth.NewCertAuthenticator())
+			}
+		}
+
+		messagesHandler = MultiAuthMiddleware(cfg.Auth, extra...)(messagesHandler)
+	case cfg.Server.APIKey != "":
 		messagesHandler = AuthMiddleware(cfg.Server.APIKey)(messagesHandler)
 	}
 
+	messagesHandler = metrics.MetricsMiddleware(reg)(messagesHandler)
 	messagesHandler = LoggingMiddleware()(messagesHandler)
 	messagesHandler = RequestIDMiddleware()(messagesHandler)
 
@@ -45,5 +93,29 @@ func SetupRoutes(cfg *config.Config, provider providers.Provider, providerKey st
 		w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// Metrics endpoint, bypassing auth like /health. Enabled by default;
+	// Server.Metrics lets operators disable it or move it off /metrics.
+	if cfg.Server.Metrics == nil || cfg.Server.Metrics.Enabled {
+		metricsPath := "/metrics"
+		if cfg.Server.Metrics != nil && cfg.Server.Metrics.Path != "" {
+			metricsPath = cfg.Server.Metrics.Path
+		}
+
+		mux.Handle("GET "+metricsPath, reg.Handler())
+	}
+
+	// Aggregated model list across every enabled provider, for client discovery.
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		var models []providers.Model
+		for _, e := range pool.All() {
+			models = append(models, e.Provider.ListModels()...)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		//nolint:errcheck // best-effort write of an already-buffered response
+		json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": models})
+	})
+
 	return mux, nil
 }
@@ -4,8 +4,11 @@ package proxy
 import (
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/omarluq/cc-relay/internal/auth"
 	"github.com/omarluq/cc-relay/internal/config"
 	"github.com/omarluq/cc-relay/internal/providers"
 )
@@ -19,9 +22,9 @@ func TestSetupRoutes_CreatesHandler(t *testing.T) {
 			APIKey: "test-key",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -39,9 +42,9 @@ func TestSetupRoutes_AuthMiddlewareApplied(t *testing.T) {
 			APIKey: "test-key",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -72,9 +75,9 @@ func TestSetupRoutes_AuthMiddlewareWithValidKey(t *testing.T) {
 			APIKey: "test-key",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", backend.URL)
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", backend.URL), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -108,9 +111,9 @@ func TestSetupRoutes_NoAuthWhenAPIKeyEmpty(t *testing.T) {
 			APIKey: "", // No auth configured
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", backend.URL)
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", backend.URL), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -135,9 +138,9 @@ func TestSetupRoutes_HealthEndpoint(t *testing.T) {
 			APIKey: "test-key", // Auth enabled
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -165,9 +168,9 @@ func TestSetupRoutes_HealthEndpointWithAuth(t *testing.T) {
 			APIKey: "test-key",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -192,9 +195,9 @@ func TestSetupRoutes_OnlyPOSTToMessages(t *testing.T) {
 			APIKey: "", // No auth for simpler test
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -218,9 +221,9 @@ func TestSetupRoutes_OnlyGETToHealth(t *testing.T) {
 			APIKey: "",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -246,9 +249,11 @@ func TestSetupRoutes_InvalidProviderBaseURL(t *testing.T) {
 	}
 
 	// Create provider with invalid base URL
-	provider := providers.NewAnthropicProvider("test", "://invalid-url")
+	pool := providers.NewPool([]providers.Entry{
+		{Provider: providers.NewAnthropicProvider("test", "://invalid-url"), Key: "backend-key"},
+	}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err == nil {
 		t.Fatal("expected error for invalid provider base URL, got nil")
 	}
@@ -266,9 +271,9 @@ func TestSetupRoutes_404ForUnknownPath(t *testing.T) {
 			APIKey: "",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -291,9 +296,9 @@ func TestSetupRoutes_MessagesPathMustBeExact(t *testing.T) {
 			APIKey: "",
 		},
 	}
-	provider := providers.NewAnthropicProvider("test", "https://api.anthropic.com")
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
 
-	handler, err := SetupRoutes(cfg, provider, "backend-key")
+	handler, err := SetupRoutes(cfg, pool)
 	if err != nil {
 		t.Fatalf("SetupRoutes failed: %v", err)
 	}
@@ -307,3 +312,179 @@ func TestSetupRoutes_MessagesPathMustBeExact(t *testing.T) {
 		t.Errorf("expected 404 for non-exact path, got %d", rec.Code)
 	}
 }
+
+func TestSetupRoutes_MetricsEndpointBypassesAuth(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			APIKey: "test-key",
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 from /metrics without auth, got %d", rec.Code)
+	}
+
+	if !strings.Contains(rec.Body.String(), "ccrelay_provider_up") {
+		t.Errorf("expected ccrelay_provider_up in /metrics output, got: %s", rec.Body.String())
+	}
+}
+
+func TestSetupRoutes_ModelsEndpointAggregatesProviders(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Server: config.ServerConfig{APIKey: ""}}
+	pool := providers.NewPool([]providers.Entry{
+		{Provider: providers.NewAnthropicProviderWithModels("primary", "https://api.anthropic.com", []string{"claude-3-5-sonnet"}), Key: "k1"},
+		{Provider: providers.NewZAIProviderWithModels("fallback", "", []string{"glm-4.6"}), Key: "k2"},
+	}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/models", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	if !strings.Contains(rec.Body.String(), "claude-3-5-sonnet") || !strings.Contains(rec.Body.String(), "glm-4.6") {
+		t.Errorf("expected aggregated models from both providers, got: %s", rec.Body.String())
+	}
+}
+
+func TestSetupRoutes_MetricsDisabledByConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Metrics: &config.MetricsConfig{Enabled: false},
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when metrics are disabled, got %d", rec.Code)
+	}
+}
+
+func TestSetupRoutes_MetricsCustomPath(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Metrics: &config.MetricsConfig{Enabled: true, Path: "/internal/metrics"},
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/internal/metrics", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 from the custom metrics path, got %d", rec.Code)
+	}
+}
+
+func TestSetupRoutes_ScopedCertAuthWiredWhenClientCertCapabilitiesConfigured(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Auth: &config.AuthConfig{},
+		Server: config.ServerConfig{
+			TLS: &config.TLSConfig{
+				ClientAuth: "require_and_verify",
+				ClientCertCapabilities: map[string]auth.KeyCapabilities{
+					"agent-1": {Models: []string{"claude-"}},
+				},
+			},
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	// No client certificate presented: the scoped cert authenticator (and no
+	// other authenticator, since Auth has no other methods configured)
+	// should reject the request.
+	req := httptest.NewRequest("POST", "/v1/messages", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a client certificate, got %d", rec.Code)
+	}
+}
+
+func TestSetupRoutes_AuditEnabledWithFileSink(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			APIKey: "test-key",
+			Audit: &config.AuditConfig{
+				Enabled: true,
+				Sink:    "file",
+				Path:    filepath.Join(t.TempDir(), "audit.log"),
+			},
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	handler, err := SetupRoutes(cfg, pool)
+	if err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	if handler == nil {
+		t.Fatal("handler is nil")
+	}
+}
+
+func TestSetupRoutes_AuditUnknownSinkErrors(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Audit: &config.AuditConfig{Enabled: true, Sink: "carrier-pigeon"},
+		},
+	}
+	pool := providers.NewPool([]providers.Entry{{Provider: providers.NewAnthropicProvider("test", "https://api.anthropic.com"), Key: "backend-key"}}, providers.HealthCheckConfig{}, providers.FailoverConfig{})
+
+	if _, err := SetupRoutes(cfg, pool); err == nil {
+		t.Fatal("expected an error for an unknown audit sink")
+	}
+}
@@ -0,0 +1,38 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import "context"
+
+// routingDecisionCtxKey is an unexported type to avoid collisions with context keys from other packages.
+type routingDecisionCtxKey struct{}
+
+// withRoutingSlot installs an empty *Decision slot into ctx for a handler
+// further down the chain to fill in via AddRoutingDecision. Because
+// http.Request contexts are immutable, a downstream handler's
+// r.WithContext(...) isn't visible to an outer middleware after
+// next.ServeHTTP returns - storing a pointer, rather than a value, lets the
+// same slot be written by the handler and read back by LoggingMiddleware.
+func withRoutingSlot(ctx context.Context) (context.Context, *Decision) {
+	slot := new(Decision)
+	return context.WithValue(ctx, routingDecisionCtxKey{}, slot), slot
+}
+
+// AddRoutingDecision records decision in the slot installed by
+// withRoutingSlot, if one is present on ctx. It is a no-op otherwise.
+func AddRoutingDecision(ctx context.Context, decision Decision) {
+	if slot, ok := ctx.Value(routingDecisionCtxKey{}).(*Decision); ok {
+		*slot = decision
+	}
+}
+
+// GetRoutingDecision returns the Decision recorded via AddRoutingDecision,
+// and whether a provider was actually chosen (the zero Decision has an
+// empty Provider).
+func GetRoutingDecision(ctx context.Context) (Decision, bool) {
+	slot, ok := ctx.Value(routingDecisionCtxKey{}).(*Decision)
+	if !ok {
+		return Decision{}, false
+	}
+
+	return *slot, slot.Provider != ""
+}
@@ -0,0 +1,260 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unixSocketPrefix marks a Server addr as a Unix domain socket path rather
+// than a TCP host:port, e.g. "unix:///var/run/cc-relay.sock".
+const unixSocketPrefix = "unix://"
+
+// defaultSocketMode is applied to a Unix socket file when SetSocketMode is
+// never called.
+const defaultSocketMode = os.FileMode(0o600)
+
+// Server wraps an *http.Server with cc-relay's plain-TCP, Unix socket, and
+// TLS bootstrap.
+type Server struct {
+	httpServer  *http.Server
+	addr        string
+	socketMode  os.FileMode
+	socketOwner string
+	socketGroup string
+	tlsConfig   *TLSConfig
+	stopWatch   chan struct{}
+	socketPath  string
+}
+
+// NewServer creates a Server listening on addr with handler. addr is either
+// a "host:port" TCP address or a "unix://" path to a Unix domain socket.
+// Call SetTLSConfig before ListenAndServeTLS to serve HTTPS instead of plain
+// HTTP.
+func NewServer(addr string, handler http.Handler) *Server {
+	return &Server{
+		addr:       addr,
+		socketMode: defaultSocketMode,
+		httpServer: &http.Server{
+			Addr:    addr,
+			Handler: handler,
+		},
+	}
+}
+
+// SetTLSConfig attaches a TLS configuration to be used by ListenAndServeTLS.
+func (s *Server) SetTLSConfig(cfg *TLSConfig) {
+	s.tlsConfig = cfg
+}
+
+// SetSocketMode sets the file mode applied to a Unix domain socket created
+// by Listen. It has no effect when addr is a TCP address. Defaults to 0600.
+func (s *Server) SetSocketMode(mode os.FileMode) {
+	s.socketMode = mode
+}
+
+// SetSocketOwner sets the owner and/or group applied to a Unix domain socket
+// created by Listen, each accepted as either a numeric ID or a user/group
+// name and resolved at listen time. Either may be left empty to leave that
+// half of the ownership unchanged from whatever the listening process's
+// umask/uid produced. It has no effect when addr is a TCP address.
+func (s *Server) SetSocketOwner(owner, group string) {
+	s.socketOwner = owner
+	s.socketGroup = group
+}
+
+// listen binds s.addr, dispatching to a Unix domain socket or a TCP listener
+// based on the "unix://" prefix. A stale socket file left behind by a
+// previous, uncleanly-terminated process is removed before binding.
+func (s *Server) listen() (net.Listener, error) {
+	path, ok := strings.CutPrefix(s.addr, unixSocketPrefix)
+	if !ok {
+		return net.Listen("tcp", s.addr)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, fmt.Errorf("failed to remove stale socket %q: %w", path, err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %q: %w", path, err)
+	}
+
+	mode := s.socketMode
+	if mode == 0 {
+		mode = defaultSocketMode
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to chmod unix socket %q: %w", path, err)
+	}
+
+	if s.socketOwner != "" || s.socketGroup != "" {
+		uid, gid, err := resolveOwnerGroup(s.socketOwner, s.socketGroup)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to resolve unix socket owner/group: %w", err)
+		}
+
+		if err := os.Chown(path, uid, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to chown unix socket %q: %w", path, err)
+		}
+	}
+
+	s.socketPath = path
+
+	return listener, nil
+}
+
+// resolveOwnerGroup resolves owner/group, each either a numeric ID or a
+// user/group name, to the uid/gid pair for os.Chown. An empty owner or group
+// resolves to -1, leaving that half unchanged, matching os.Chown's own
+// convention for "don't change this".
+func resolveOwnerGroup(owner, group string) (uid, gid int, err error) {
+	uid = -1
+	gid = -1
+
+	if owner != "" {
+		uid, err = lookupID(owner, user.Lookup, func(u *user.User) string { return u.Uid })
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to look up socket owner %q: %w", owner, err)
+		}
+	}
+
+	if group != "" {
+		gid, err = lookupID(group, user.LookupGroup, func(g *user.Group) string { return g.Gid })
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to look up socket group %q: %w", group, err)
+		}
+	}
+
+	return uid, gid, nil
+}
+
+// lookupID resolves name to a numeric ID, trying a plain numeric parse
+// before falling back to lookup (user.Lookup or user.LookupGroup).
+func lookupID[T any](name string, lookup func(string) (T, error), id func(T) string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		return n, nil
+	}
+
+	entry, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(id(entry))
+}
+
+// removeStaleSocket unlinks path if it exists and is a socket, so a process
+// restart can rebind without a stale-file "address already in use" error.
+// It leaves non-socket files alone rather than risk deleting the wrong thing.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%q exists and is not a socket", path)
+	}
+
+	return os.Remove(path)
+}
+
+// ListenAndServe starts serving plain HTTP until the server is shut down.
+func (s *Server) ListenAndServe() error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("listen", s.addr).Msg("serving HTTP")
+
+	return s.httpServer.Serve(listener)
+}
+
+// ListenAndServeTLS starts serving HTTPS using the TLSConfig set via
+// SetTLSConfig, reloading the certificate from disk whenever it changes
+// without dropping established connections. ctx bounds the certificate
+// watcher's lifetime; it is stopped on Shutdown.
+func (s *Server) ListenAndServeTLS(ctx context.Context) error {
+	if s.tlsConfig == nil {
+		return errors.New("ListenAndServeTLS called without a TLSConfig; call SetTLSConfig first")
+	}
+
+	stdTLSConfig, reloader, err := s.tlsConfig.GetTLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to build TLS config: %w", err)
+	}
+
+	s.stopWatch = make(chan struct{})
+	if err := reloader.watch(s.stopWatch); err != nil {
+		return fmt.Errorf("failed to start certificate watcher: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		close(s.stopWatch)
+	}()
+
+	s.httpServer.TLSConfig = stdTLSConfig
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	listener = tls.NewListener(listener, stdTLSConfig)
+
+	log.Info().Str("listen", s.addr).Bool("mtls", stdTLSConfig.ClientAuth != tls.NoClientCert).
+		Msg("serving TLS")
+
+	return s.httpServer.Serve(listener)
+}
+
+// Shutdown gracefully stops the server, waiting for in-flight requests to
+// complete or ctx to expire. A Unix domain socket file is removed afterward.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.stopWatch != nil {
+		select {
+		case <-s.stopWatch:
+		default:
+			close(s.stopWatch)
+		}
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+
+	if s.socketPath != "" {
+		if rmErr := os.Remove(s.socketPath); rmErr != nil && !os.IsNotExist(rmErr) {
+			log.Warn().Err(rmErr).Str("path", s.socketPath).Msg("failed to remove unix socket on shutdown")
+		}
+	}
+
+	return err
+}
+
+// Listener is exposed for tests that want to bind s.addr before calling
+// Serve directly. It dispatches to a Unix domain socket or a TCP listener
+// exactly as ListenAndServe does.
+func (s *Server) Listener() (net.Listener, error) {
+	return s.listen()
+}
@@ -0,0 +1,186 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServer_ListenAndServeOverUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "cc-relay.sock")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	server := NewServer("unix://"+socketPath, mux)
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	waitForSocket(t, socketPath)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://unix/health")
+	if err != nil {
+		t.Fatalf("request over unix socket failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_RemovesStaleSocketOnStartup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "cc-relay.sock")
+
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to create stale socket: %v", err)
+	}
+	stale.Close() // leaves the socket file behind, simulating an unclean exit
+
+	server := NewServer("unix://"+socketPath, http.NewServeMux())
+
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatalf("expected stale socket to be removed and rebound, got: %v", err)
+	}
+	defer listener.Close()
+}
+
+func TestServer_SocketModeAppliedToFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "cc-relay.sock")
+
+	server := NewServer("unix://"+socketPath, http.NewServeMux())
+	server.SetSocketMode(0o640)
+
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("expected socket mode 0640, got %o", info.Mode().Perm())
+	}
+}
+
+func TestServer_SocketOwnerAppliedToFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "cc-relay.sock")
+
+	// Chowning to anything other than the current uid/gid requires
+	// privileges this test shouldn't need, so exercise the numeric-ID path
+	// against the process's own identity - enough to prove listen() issues
+	// the chown at all.
+	uid := strconv.Itoa(os.Getuid())
+	gid := strconv.Itoa(os.Getgid())
+
+	server := NewServer("unix://"+socketPath, http.NewServeMux())
+	server.SetSocketOwner(uid, gid)
+
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Fatal("expected a *syscall.Stat_t from Stat().Sys()")
+	}
+
+	if int(stat.Uid) != os.Getuid() || int(stat.Gid) != os.Getgid() {
+		t.Errorf("expected socket owned by uid=%d gid=%d, got uid=%d gid=%d", os.Getuid(), os.Getgid(), stat.Uid, stat.Gid)
+	}
+}
+
+func TestServer_ShutdownRemovesSocketFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "cc-relay.sock")
+
+	server := NewServer("unix://"+socketPath, http.NewServeMux())
+
+	go server.ListenAndServe()
+
+	waitForSocket(t, socketPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("expected socket file to be removed after shutdown, stat err: %v", err)
+	}
+}
+
+// waitForSocket polls until path exists, for tests that start a listener on
+// a background goroutine.
+func waitForSocket(t *testing.T, path string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for socket %q to appear", path)
+}
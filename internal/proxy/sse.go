@@ -2,8 +2,11 @@
 package proxy
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
+
+	"github.com/omarluq/cc-relay/internal/metrics"
 )
 
 // IsStreamingRequest checks if request body contains "stream": true.
@@ -27,3 +30,59 @@ func SetSSEHeaders(h http.Header) {
 	h.Set("X-Accel-Buffering", "no")
 	h.Set("Connection", "keep-alive")
 }
+
+// sseDataPrefix precedes the JSON payload on an SSE data line.
+var sseDataPrefix = []byte("data:")
+
+// ParseUsageEvent extracts token usage from a single line of an Anthropic SSE
+// stream. It recognizes the "usage" object carried by both message_start
+// (input_tokens, cache_creation_input_tokens, cache_read_input_tokens) and
+// message_delta (output_tokens) events. The caller is expected to call this
+// for every line of a streamed response and merge the results as usage fields
+// arrive across events; each field is a running total, so the latest non-zero
+// value wins (see mergeTokenUsage) and the merged usage is reported once via
+// metrics.Registry.ObserveTokenUsage. It returns ok=false for lines that
+// aren't a data line, or that don't carry a usage object.
+func ParseUsageEvent(line []byte) (usage metrics.TokenUsage, ok bool) {
+	payload, found := bytes.CutPrefix(bytes.TrimSpace(line), sseDataPrefix)
+	if !found {
+		return metrics.TokenUsage{}, false
+	}
+
+	var event struct {
+		Usage *struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+		Message *struct {
+			Usage *struct {
+				InputTokens              int `json:"input_tokens"`
+				OutputTokens             int `json:"output_tokens"`
+				CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+				CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			} `json:"usage"`
+		} `json:"message"`
+	}
+
+	if err := json.Unmarshal(bytes.TrimSpace(payload), &event); err != nil {
+		return metrics.TokenUsage{}, false
+	}
+
+	u := event.Usage
+	if u == nil && event.Message != nil {
+		u = event.Message.Usage
+	}
+
+	if u == nil {
+		return metrics.TokenUsage{}, false
+	}
+
+	return metrics.TokenUsage{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}, true
+}
@@ -0,0 +1,52 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import "testing"
+
+func TestParseUsageEvent_MessageStart(t *testing.T) {
+	t.Parallel()
+
+	line := []byte(`data: {"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1,"cache_creation_input_tokens":3,"cache_read_input_tokens":7}}}`)
+
+	usage, ok := ParseUsageEvent(line)
+	if !ok {
+		t.Fatal("expected message_start usage to be parsed")
+	}
+
+	if usage.InputTokens != 25 || usage.CacheWriteTokens != 3 || usage.CacheReadTokens != 7 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestParseUsageEvent_MessageDelta(t *testing.T) {
+	t.Parallel()
+
+	line := []byte(`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":42}}`)
+
+	usage, ok := ParseUsageEvent(line)
+	if !ok {
+		t.Fatal("expected message_delta usage to be parsed")
+	}
+
+	if usage.OutputTokens != 42 {
+		t.Errorf("expected 42 output tokens, got %d", usage.OutputTokens)
+	}
+}
+
+func TestParseUsageEvent_IgnoresNonDataLines(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := ParseUsageEvent([]byte("event: message_start")); ok {
+		t.Error("expected a non-data line to be ignored")
+	}
+}
+
+func TestParseUsageEvent_IgnoresEventsWithoutUsage(t *testing.T) {
+	t.Parallel()
+
+	line := []byte(`data: {"type":"content_block_delta","delta":{"text":"hi"}}`)
+
+	if _, ok := ParseUsageEvent(line); ok {
+		t.Error("expected an event without a usage object to be ignored")
+	}
+}
@@ -0,0 +1,183 @@
+// Package proxy implements the HTTP proxy server for cc-relay.
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// ClientAuthType names the mTLS client certificate policy, mirroring
+// crypto/tls.ClientAuthType but expressed as the YAML-friendly strings used
+// in cc-relay's config.
+type ClientAuthType string
+
+const (
+	ClientAuthNo               ClientAuthType = "no"
+	ClientAuthRequest          ClientAuthType = "request"
+	ClientAuthRequire          ClientAuthType = "require"
+	ClientAuthVerifyIfGiven    ClientAuthType = "verify_if_given"
+	ClientAuthRequireAndVerify ClientAuthType = "require_and_verify"
+)
+
+func (t ClientAuthType) toStdlib() tls.ClientAuthType {
+	switch t {
+	case ClientAuthRequest:
+		return tls.RequestClientCert
+	case ClientAuthRequire:
+		return tls.RequireAnyClientCert
+	case ClientAuthVerifyIfGiven:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequireAndVerify:
+		return tls.RequireAndVerifyClientCert
+	case ClientAuthNo:
+		return tls.NoClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// TLSConfig configures the server's listening TLS and optional mTLS policy.
+type TLSConfig struct {
+	CertFile         string
+	KeyFile          string
+	ClientCAFile     string
+	ClientAuth       ClientAuthType
+	MinVersion       uint16 // a crypto/tls TLS version constant, e.g. tls.VersionTLS12
+	CipherSuites     []uint16
+	CurvePreferences []tls.CurveID
+}
+
+// certReloader watches CertFile/KeyFile on disk and keeps an atomically
+// swappable *tls.Certificate so in-flight connections are never dropped on
+// rotation.
+type certReloader struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate/key pair: %w", err)
+	}
+
+	r.cert.Store(&cert)
+
+	return nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// watch reloads the certificate whenever certFile or keyFile changes on
+// disk, until stop is closed. Reload failures are logged and the previous
+// (still valid) certificate keeps serving.
+func (r *certReloader) watch(stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create certificate watcher: %w", err)
+	}
+
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if err := watcher.Add(f); err != nil {
+			watcher.Close() //nolint:errcheck // best-effort cleanup on setup failure
+
+			return fmt.Errorf("failed to watch %q: %w", f, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck // best-effort cleanup
+
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				if err := r.reload(); err != nil {
+					log.Error().Err(err).Msg("failed to reload TLS certificate")
+				} else {
+					log.Info().Msg("reloaded TLS certificate")
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Error().Err(err).Msg("certificate watcher error")
+			}
+		}
+	}()
+
+	return nil
+}
+
+// GetTLSConfig builds a *tls.Config for cfg, sourcing the server certificate
+// from a hot-reloadable cache via GetCertificate and, when ClientCAFile is
+// set, requiring client certificates per cfg.ClientAuth.
+func (cfg *TLSConfig) GetTLSConfig() (*tls.Config, *certReloader, error) {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		GetCertificate:   reloader.getCertificate,
+		MinVersion:       cfg.MinVersion,
+		CipherSuites:     cfg.CipherSuites,
+		CurvePreferences: cfg.CurvePreferences,
+	}
+
+	if tlsCfg.MinVersion == 0 {
+		tlsCfg.MinVersion = tls.VersionTLS12
+	}
+
+	if cfg.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, nil, fmt.Errorf("no certificates found in client CA file %q", cfg.ClientCAFile)
+		}
+
+		tlsCfg.ClientCAs = pool
+
+		clientAuth := cfg.ClientAuth
+		if clientAuth == "" || clientAuth == ClientAuthNo {
+			clientAuth = ClientAuthRequireAndVerify
+		}
+
+		tlsCfg.ClientAuth = clientAuth.toStdlib()
+	} else {
+		tlsCfg.ClientAuth = cfg.ClientAuth.toStdlib()
+	}
+
+	return tlsCfg, reloader, nil
+}